@@ -0,0 +1,138 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// resultCacheKey identifies a search whose results are cached
+type resultCacheKey struct {
+	latitude, longitude, distanceMeters float64
+	params                              SearchCoveringParameters
+}
+
+// resultCacheEntry holds the results of a cached search along with the covering they were found in
+type resultCacheEntry struct {
+	expiresAt  time.Time
+	items      []interface{}
+	cellBounds SearchCoveringResult
+	cellUnion  s2.CellUnion
+}
+
+// resultCache caches the results of ItemsWithinDistance. It has its own mutex because entries are added by
+// searches, which only hold the collection's read lock. Entries are added while the read lock is held and removed
+// by writers while the write lock is held, so a search can never cache results that a concurrent write has made
+// stale.
+type resultCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[resultCacheKey]resultCacheEntry
+}
+
+// WithResultCacheTTL caches the results of ItemsWithinDistance for up to ttl, so that bursts of identical
+// searches only search the index once. Every write to the collection drops the cached results whose covering
+// includes the cell of the item written, so cached results never miss a write; ttl bounds how long results are
+// kept regardless, and so how much memory bursts of distinct searches can hold on to. Writes cost an extra check
+// of every cached search. A ttl of zero or less disables caching, which is the default.
+func WithResultCacheTTL(ttl time.Duration) Option {
+	return func(c *Collection) {
+		if ttl <= 0 {
+			c.cache = nil
+			return
+		}
+		c.cache = &resultCache{ttl: ttl, entries: make(map[resultCacheKey]resultCacheEntry)}
+	}
+}
+
+// cachedItemsWithinDistance performs ItemsWithinDistance through the result cache
+func (c Collection) cachedItemsWithinDistance(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) ([]interface{}, SearchCoveringResult) {
+	key := resultCacheKey{latitude: latitude, longitude: longitude, distanceMeters: distanceMeters, params: params}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := c.now()
+	if entry, ok := c.cache.get(key, now); ok {
+		return c.copiedItems(entry.items), entry.cellBounds
+	}
+
+	center := NewPointFromLatLng(latitude, longitude)
+	cellUnion := params.covering(params.searchCap(center, distanceMeters, c.radiusMeters))
+	// the results must not outlive the first item to expire among them
+	expiresAt := now.Add(c.cache.ttl)
+	c.eachInCovering(cellUnion, func(_ interface{}, item collectionContents) bool {
+		if !item.expiresAt.IsZero() && item.expiresAt.Before(expiresAt) {
+			expiresAt = item.expiresAt
+		}
+		return true
+	})
+	entry := resultCacheEntry{
+		expiresAt:  expiresAt,
+		items:      c.itemsNear(center, cellUnion, params),
+		cellBounds: coveringResult(cellUnion, params.MergeCovering),
+		// writes are matched against the cells the search read, which are coarser than the covering when finer
+		// levels are not indexed
+		cellUnion: c.indexedCovering(cellUnion),
+	}
+	c.cache.put(key, entry)
+	return c.copiedItems(entry.items), entry.cellBounds
+}
+
+// copiedItems copies the cached contents of a search, so that callers can't mutate what later searches are served
+func (c Collection) copiedItems(items []interface{}) []interface{} {
+	foundItems := make([]interface{}, 0, len(items))
+	for _, contents := range items {
+		foundItems = append(foundItems, c.copied(contents))
+	}
+	return foundItems
+}
+
+// get returns the entry cached for key if it has not expired
+func (rc *resultCache) get(key resultCacheKey, now time.Time) (resultCacheEntry, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		return resultCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put caches an entry for key
+func (rc *resultCache) put(key resultCacheKey, entry resultCacheEntry) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.entries[key] = entry
+}
+
+// invalidate drops the cached entries whose covering includes cellID, along with any that have expired. The
+// caller must hold the collection's write lock.
+func (c Collection) invalidate(cellID s2.CellID) {
+	if c.cache == nil {
+		return
+	}
+	now := c.now()
+	c.cache.mutex.Lock()
+	defer c.cache.mutex.Unlock()
+	for key, entry := range c.cache.entries {
+		if !now.Before(entry.expiresAt) || entry.cellUnion.ContainsCellID(cellID) {
+			delete(c.cache.entries, key)
+		}
+	}
+}
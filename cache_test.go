@@ -0,0 +1,119 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResultCacheTTL(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	search := func(cl Collection) []interface{} {
+		found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+		return found
+	}
+	// dropFromIndex removes a key from the index behind the collection's back, which only searches that are not
+	// served from the cache notice
+	dropFromIndex := func(cl Collection, key interface{}) {
+		for _, index := range cl.keys[key] {
+			delete(cl.cells[index.cellLevel][index.cellID], key)
+		}
+	}
+
+	t.Run("Identical searches are served from the cache until the TTL lapses", func(t *testing.T) {
+		cl := NewCollection(WithResultCacheTTL(time.Minute), WithClock(clock))
+		cl.Set(0, "0", cell1.lat, cell1.lon)
+		assert.Equal(t, []interface{}{"0"}, search(cl))
+		dropFromIndex(cl, 0)
+		now = now.Add(59 * time.Second)
+		assert.Equal(t, []interface{}{"0"}, search(cl))
+		now = now.Add(time.Second)
+		assert.Empty(t, search(cl))
+	})
+	t.Run("Writes in the covering invalidate cached results immediately", func(t *testing.T) {
+		cl := NewCollection(WithResultCacheTTL(time.Minute), WithClock(clock))
+		cl.Set(0, "0", cell1.lat, cell1.lon)
+		assert.Equal(t, []interface{}{"0"}, search(cl))
+		cl.Set(0, "updated", cell1.lat, cell1.lon)
+		assert.Equal(t, []interface{}{"updated"}, search(cl))
+		cl.Set(1, "1", cell1.lat, cell1.lon)
+		assert.ElementsMatch(t, []interface{}{"updated", "1"}, search(cl))
+		cl.Delete(0)
+		assert.Equal(t, []interface{}{"1"}, search(cl))
+		cl.Set(1, "1", cell2.lat, cell2.lon)
+		assert.Empty(t, search(cl))
+	})
+	t.Run("Writes outside of the covering keep cached results", func(t *testing.T) {
+		cl := NewCollection(WithResultCacheTTL(time.Minute), WithClock(clock))
+		cl.Set(0, "0", cell1.lat, cell1.lon)
+		assert.Equal(t, []interface{}{"0"}, search(cl))
+		dropFromIndex(cl, 0)
+		cl.Set(1, "1", cell2.lat, cell2.lon)
+		assert.Equal(t, []interface{}{"0"}, search(cl))
+	})
+	t.Run("Writes in the indexed cells of the covering invalidate cached results", func(t *testing.T) {
+		cl := NewCollection(WithIndexLevels(0, 10), WithResultCacheTTL(time.Minute), WithClock(clock))
+		cl.Set(0, 0, cell1.lat, cell1.lon)
+		assert.Equal(t, []interface{}{0}, search(cl))
+
+		// a point in the level 10 cell that the search reads, but outside of the covering itself
+		covering := params.covering(params.searchCap(NewPointFromLatLng(cell1.lat, cell1.lon), 1000, cl.radiusMeters))
+		parent := cell1.cellID.Parent(10)
+		var outside s2.LatLng
+		for child := parent.ChildBeginAtLevel(14); child != parent.ChildEndAtLevel(14); child = child.Next() {
+			if !covering.ContainsCellID(child) && !covering.IntersectsCellID(child) {
+				outside = child.LatLng()
+				break
+			}
+		}
+		require.NotZero(t, outside)
+		cl.Set(1, 1, outside.Lat.Degrees(), outside.Lng.Degrees())
+		assert.ElementsMatch(t, []interface{}{0, 1}, search(cl))
+		uncached := NewCollection(WithIndexLevels(0, 10))
+		uncached.Set(0, 0, cell1.lat, cell1.lon)
+		uncached.Set(1, 1, outside.Lat.Degrees(), outside.Lng.Degrees())
+		assert.ElementsMatch(t, search(uncached), search(cl))
+	})
+	t.Run("Mutating the results of a search does not change the cached results", func(t *testing.T) {
+		cl := NewCollection(WithResultCacheTTL(time.Minute), WithClock(clock), WithContentsCopier(
+			func(contents interface{}) interface{} {
+				spot := *contents.(*testSpot)
+				return &spot
+			}))
+		cl.Set(0, &testSpot{name: "original"}, cell1.lat, cell1.lon)
+		missed := search(cl)
+		require.Len(t, missed, 1)
+		missed[0].(*testSpot).name = "mutated"
+		hit := search(cl)
+		require.Len(t, hit, 1)
+		assert.Equal(t, "original", hit[0].(*testSpot).name)
+		hit[0].(*testSpot).name = "mutated"
+		assert.Equal(t, "original", search(cl)[0].(*testSpot).name)
+	})
+	t.Run("Results are not cached without a TTL", func(t *testing.T) {
+		cl := NewCollection(WithResultCacheTTL(0))
+		cl.Set(0, "0", cell1.lat, cell1.lon)
+		assert.Equal(t, []interface{}{"0"}, search(cl))
+		dropFromIndex(cl, 0)
+		assert.Empty(t, search(cl))
+	})
+}
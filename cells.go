@@ -0,0 +1,177 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// levelCovering covers region with cells of exactly the given level. Since the coverer cannot use larger cells,
+// the covering contains every cell of the level that intersects the region regardless of MaxCells.
+func levelCovering(region s2.Region, level int) s2.CellUnion {
+	coverer := s2.RegionCoverer{MinLevel: level, MaxLevel: level, LevelMod: 1, MaxCells: 1}
+	return coverer.Covering(region)
+}
+
+// DensestCell returns the cell of the given level within region that contains the most items, along with the
+// number of items in it. Cells are counted whole, so items in the part of a cell that extends past the edge of
+// the region count as well. Ties are broken in favor of the lowest cell id. ok is false when no cell of the region
+// contains items or the level is not one of the levels the collection indexes.
+func (c Collection) DensestCell(region s2.Region, level int) (cell s2.CellID, count int, ok bool) {
+	if !c.isIndexedLevel(level) {
+		return 0, 0, false
+	}
+	cellUnion := levelCovering(region, level)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, cellID := range cellUnion {
+		if n := len(c.cells[level][cellID]); n > count {
+			cell, count = cellID, n
+		}
+	}
+	return cell, count, count > 0
+}
+
+// CoverageGaps returns the cells of the given level within region whose center has no item within radiusMeters,
+// in order of cell id. It runs a radius search for every cell of sampleLevel that intersects region, so its cost
+// grows with the number of those cells, which quadruples with each finer level. Invalid levels return no cells.
+func (c Collection) CoverageGaps(region s2.Region, sampleLevel int, radiusMeters float64) []s2.CellID {
+	gaps := make([]s2.CellID, 0)
+	if sampleLevel < 0 || sampleLevel > maxCellLevel {
+		return gaps
+	}
+	samples := levelCovering(region, sampleLevel)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, cellID := range samples {
+		center := cellID.Point()
+		covered := false
+		c.eachInCovering(nearestParams.covering(capFromCenterMeters(center, radiusMeters, c.radiusMeters)),
+			func(_ interface{}, item collectionContents) bool {
+				covered = c.distance(center, item.point) <= radiusMeters
+				return !covered
+			})
+		if !covered {
+			gaps = append(gaps, cellID)
+		}
+	}
+	return gaps
+}
+
+// BoundingCell returns the smallest cell that contains every one of the items, i.e. the common ancestor of their
+// leaf cells, which is useful to frame the results of a search on a map or as a cache key for them. ok is false
+// when there are no items or the items are on different cube faces, in which case only the whole sphere contains
+// them all.
+func BoundingCell(items []LocatedItem) (cell s2.CellID, ok bool) {
+	for i, item := range items {
+		leaf := s2.CellIDFromLatLng(s2.LatLngFromDegrees(item.Latitude, item.Longitude))
+		if i == 0 {
+			cell = leaf
+			continue
+		}
+		level, found := cell.CommonAncestorLevel(leaf)
+		if !found {
+			return 0, false
+		}
+		cell = cell.Parent(level)
+	}
+	return cell, len(items) > 0
+}
+
+// CellCount is an occupied cell, the number of items in it and its distance from a point
+type CellCount struct {
+	Cell s2.CellID
+	// Token is the token of Cell, see s2.CellID.ToToken
+	Token string
+	Count int
+	// DistanceMeters is the distance from the point searched to the center of the cell. It is only set by
+	// KNearestCells.
+	DistanceMeters float64
+}
+
+// KNearestCells returns the k occupied cells of the given level whose centers are nearest to the given latitude
+// and longitude, along with the number of items in each, ordered by the distance to their centers and then by
+// cell id. Every occupied cell of the level is measured, so the cost grows with the number of cells of the level
+// that hold items. Levels the collection does not index return no cells.
+func (c Collection) KNearestCells(latitude, longitude float64, level, k int) []CellCount {
+	found := make([]CellCount, 0)
+	if !c.isIndexedLevel(level) || k <= 0 {
+		return found
+	}
+	point := NewPointFromLatLng(latitude, longitude)
+
+	c.mutex.RLock()
+	for cellID, keys := range c.cells[level] {
+		if len(keys) == 0 {
+			continue
+		}
+		found = append(found, CellCount{
+			Cell:           cellID,
+			Token:          cellID.ToToken(),
+			Count:          len(keys),
+			DistanceMeters: c.distance(point, cellID.Point()),
+		})
+	}
+	c.mutex.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].DistanceMeters != found[j].DistanceMeters {
+			return found[i].DistanceMeters < found[j].DistanceMeters
+		}
+		return found[i].Cell < found[j].Cell
+	})
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+// TopCells returns the n cells of the given level that hold the most items, along with the number of items in
+// each, ordered by decreasing count and then by cell id. Like KNearestCells, every occupied cell of the level is
+// counted, so the cost grows with the number of cells of the level that hold items. Levels the collection does not
+// index return no cells.
+func (c Collection) TopCells(level, n int) []CellCount {
+	found := make([]CellCount, 0)
+	if !c.isIndexedLevel(level) || n <= 0 {
+		return found
+	}
+
+	c.mutex.RLock()
+	for cellID, keys := range c.cells[level] {
+		if len(keys) == 0 {
+			continue
+		}
+		found = append(found, CellCount{Cell: cellID, Count: len(keys)})
+	}
+	c.mutex.RUnlock()
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Count != found[j].Count {
+			return found[i].Count > found[j].Count
+		}
+		return found[i].Cell < found[j].Cell
+	})
+	if len(found) > n {
+		found = found[:n]
+	}
+	for i := range found {
+		found[i].Token = found[i].Cell.ToToken()
+	}
+	return found
+}
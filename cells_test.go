@@ -0,0 +1,219 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_DensestCell(t *testing.T) {
+	const level = 12
+	busy := cell1.cellID.Parent(level)
+	quiet := busy.EdgeNeighbors()[1]
+	cl := NewCollection()
+	children := busy.Children()
+	for i, child := range children[:3] {
+		ll := child.LatLng()
+		cl.Set(i, i, ll.Lat.Degrees(), ll.Lng.Degrees())
+	}
+	ll := quiet.LatLng()
+	cl.Set(3, 3, ll.Lat.Degrees(), ll.Lng.Degrees())
+	// Manhattan items are far outside the search region
+	for i := 4; i < 10; i++ {
+		cl.Set(i, i, cell2.lat, cell2.lon)
+	}
+	var params SearchCoveringParameters
+	chicago := NewPointFromLatLng(cell1.lat, cell1.lon)
+
+	tests := []struct {
+		name          string
+		region        s2.Region
+		level         int
+		expectedCell  s2.CellID
+		expectedCount int
+		expectedOK    bool
+	}{
+		{
+			name:          "Densest cell in the region is returned",
+			region:        params.searchCap(chicago, 10000, cl.radiusMeters),
+			level:         level,
+			expectedCell:  busy,
+			expectedCount: 3,
+			expectedOK:    true,
+		}, {
+			name:          "Only cells in the region are considered",
+			region:        s2.CellFromCellID(quiet),
+			level:         level,
+			expectedCell:  quiet,
+			expectedCount: 1,
+			expectedOK:    true,
+		}, {
+			name:   "Region without items is not ok",
+			region: params.searchCap(NewPointFromLatLng(0, 0), 10000, cl.radiusMeters),
+			level:  level,
+		}, {
+			name:   "Invalid level is not ok",
+			region: params.searchCap(chicago, 10000, cl.radiusMeters),
+			level:  maxCellLevel + 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cell, count, ok := cl.DensestCell(test.region, test.level)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedCount, count)
+			assert.Equal(t, test.expectedCell, cell)
+		})
+	}
+}
+
+func TestCollection_CoverageGaps(t *testing.T) {
+	cl := NewCollection()
+	// a 2x2 block of level 12 cells around downtown Chicago, with an item at the center of all but the first
+	block := cell1.cellID.Parent(11).Children()
+	for i, cellID := range block[1:] {
+		center := cellID.LatLng()
+		cl.Set(i, i, center.Lat.Degrees(), center.Lng.Degrees())
+	}
+	region := s2.CellFromCellID(cell1.cellID.Parent(11))
+	tests := []struct {
+		name     string
+		level    int
+		radius   float64
+		expected []s2.CellID
+	}{
+		{name: "Cells without an item in range are gaps", level: 12, radius: 100, expected: block[:1]},
+		{name: "Larger radii close gaps", level: 12, radius: 5000, expected: []s2.CellID{}},
+		{name: "Invalid levels have no gaps", level: maxCellLevel + 1, radius: 100, expected: []s2.CellID{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.CoverageGaps(region, test.level, test.radius))
+		})
+	}
+}
+
+func TestBoundingCell(t *testing.T) {
+	chicago := LocatedItem{Latitude: cell1.lat, Longitude: cell1.lon}
+	tests := []struct {
+		name       string
+		items      []LocatedItem
+		expected   s2.CellID
+		expectedOk bool
+	}{
+		{
+			name:       "A single item is bounded by its leaf cell",
+			items:      []LocatedItem{chicago},
+			expected:   cell1.cellID,
+			expectedOk: true,
+		}, {
+			name:       "Items on the same face are bounded by their common ancestor",
+			items:      []LocatedItem{chicago, {Latitude: cell2.lat, Longitude: cell2.lon}},
+			expected:   cell1.cellID.Parent(2),
+			expectedOk: true,
+		}, {
+			name:  "Items on different faces are only bounded by the whole sphere",
+			items: []LocatedItem{chicago, {Latitude: 0, Longitude: 0}},
+		}, {
+			name: "No items have no bounding cell",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cell, ok := BoundingCell(test.items)
+			assert.Equal(t, test.expectedOk, ok)
+			assert.Equal(t, test.expected, cell)
+			for _, item := range test.items {
+				assert.True(t, cell.Contains(s2.CellIDFromLatLng(s2.LatLngFromDegrees(item.Latitude, item.Longitude))))
+			}
+		})
+	}
+}
+
+func TestCollection_KNearestCells(t *testing.T) {
+	cl := NewCollection()
+	// two items in downtown Chicago's level 10 cell, one in Manhattan's and an emptied one on another face
+	cl.Set(0, 0, cell1.lat, cell1.lon)
+	cl.Set(1, 1, cell1.lat, cell1.lon)
+	cl.Set(2, 2, cell2.lat, cell2.lon)
+	cl.Set(3, 3, 0, 0)
+	cl.Delete(3)
+	chicago := CellCount{Cell: cell1.cellID.Parent(10), Token: cell1.cellID.Parent(10).ToToken(), Count: 2}
+	manhattan := CellCount{Cell: cell2.cellID.Parent(10), Token: cell2.cellID.Parent(10).ToToken(), Count: 1}
+	point := NewPointFromLatLng(cell1.lat, cell1.lon)
+	chicago.DistanceMeters = EarthDistanceMeters(point, chicago.Cell.Point())
+	manhattan.DistanceMeters = EarthDistanceMeters(point, manhattan.Cell.Point())
+
+	tests := []struct {
+		name     string
+		level    int
+		k        int
+		expected []CellCount
+	}{
+		{name: "Occupied cells are ordered by distance", level: 10, k: 5, expected: []CellCount{chicago, manhattan}},
+		{name: "Only k cells are returned", level: 10, k: 1, expected: []CellCount{chicago}},
+		{name: "Invalid levels have no cells", level: -1, k: 5, expected: []CellCount{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.KNearestCells(cell1.lat, cell1.lon, test.level, test.k))
+		})
+	}
+}
+
+func TestCollection_TopCells(t *testing.T) {
+	cl := NewCollectionWithLevels(8, 16)
+	// three items in downtown Chicago's level 10 cell, one each in Manhattan's and the equator's, and an emptied
+	// one on another face
+	for i := 0; i < 3; i++ {
+		cl.Set(i, i, cell1.lat, cell1.lon)
+	}
+	cl.Set(3, 3, cell2.lat, cell2.lon)
+	cl.Set(4, 4, 0, 0)
+	cl.Set(5, 5, 0, 90)
+	cl.Delete(5)
+	cellCount := func(cellID s2.CellID, count int) CellCount {
+		return CellCount{Cell: cellID, Token: cellID.ToToken(), Count: count}
+	}
+	chicago := cellCount(cell1.cellID.Parent(10), 3)
+	manhattan := cellCount(cell2.cellID.Parent(10), 1)
+	equator := cellCount(s2.CellIDFromLatLng(s2.LatLngFromDegrees(0, 0)).Parent(10), 1)
+	// ties are ordered by cell id
+	tied := []CellCount{manhattan, equator}
+	if equator.Cell < manhattan.Cell {
+		tied = []CellCount{equator, manhattan}
+	}
+
+	tests := []struct {
+		name     string
+		level    int
+		n        int
+		expected []CellCount
+	}{
+		{name: "Occupied cells are ordered by count", level: 10, n: 5, expected: append([]CellCount{chicago}, tied...)},
+		{name: "Only n cells are returned", level: 10, n: 2, expected: []CellCount{chicago, tied[0]}},
+		{name: "No cells are requested", level: 10, n: 0, expected: []CellCount{}},
+		{name: "Levels that are not indexed have no cells", level: 20, n: 5, expected: []CellCount{}},
+		{name: "Invalid levels have no cells", level: -1, n: 5, expected: []CellCount{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.TopCells(test.level, test.n))
+		})
+	}
+}
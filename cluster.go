@@ -0,0 +1,119 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sort"
+)
+
+// ClusterComponents groups the keys in the collection into clusters where every key is within maxGapMeters of at
+// least one other key of its cluster, also known as single-linkage clustering. Items without any other item
+// within maxGapMeters form clusters of their own. Neighbors are found with the index, so the cost grows with the
+// number of items times the number of neighbors each has rather than with the square of the number of items. Keys
+// within a cluster are ordered, and clusters are ordered by their first key.
+func (c Collection) ClusterComponents(maxGapMeters float64) [][]interface{} {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	// parent links each key towards the representative key of its cluster
+	parent := make(map[interface{}]interface{}, len(c.items))
+	var find func(key interface{}) interface{}
+	find = func(key interface{}) interface{} {
+		if parent[key] == key {
+			return key
+		}
+		root := find(parent[key])
+		parent[key] = root
+		return root
+	}
+	keys := c.liveKeys()
+	for _, key := range keys {
+		parent[key] = key
+	}
+
+	for _, key := range keys {
+		position := c.items[key].point
+		cellUnion := nearestParams.covering(capFromCenterMeters(position, maxGapMeters, c.radiusMeters))
+		c.eachInCovering(cellUnion, func(neighborKey interface{}, neighbor collectionContents) bool {
+			if neighborKey == key {
+				return true
+			}
+			if c.distance(position, neighbor.point) > maxGapMeters {
+				return true
+			}
+			if root, neighborRoot := find(key), find(neighborKey); root != neighborRoot {
+				parent[neighborRoot] = root
+			}
+			return true
+		})
+	}
+
+	members := make(map[interface{}][]interface{})
+	for _, key := range keys {
+		root := find(key)
+		members[root] = append(members[root], key)
+	}
+	clusters := make([][]interface{}, 0, len(members))
+	for _, cluster := range members {
+		sort.Slice(cluster, func(i, j int) bool { return compareKeys(cluster[i], cluster[j]) < 0 })
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return compareKeys(clusters[i][0], clusters[j][0]) < 0 })
+	return clusters
+}
+
+// NeighborhoodDensity is an item found by ItemsByNeighborhoodDensity along with the number of other items within
+// the radius around it
+type NeighborhoodDensity struct {
+	Item          LocatedItem
+	NeighborCount int
+}
+
+// ItemsByNeighborhoodDensity returns every item in the collection along with the number of other items within
+// radiusMeters of it, ordered by that count from the most to the least crowded and then by key. The returned items
+// have a DistanceMeters of zero. This runs one radius search per item, so its cost is the number of items times
+// the cost of a search with the given params, and it should only be called on collections of bounded size, such as
+// one loaded with just the items of the area being reported on.
+func (c Collection) ItemsByNeighborhoodDensity(
+	radiusMeters float64, params SearchCoveringParameters,
+) []NeighborhoodDensity {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := c.now()
+	densities := make([]NeighborhoodDensity, 0, len(c.items))
+	for key, item := range c.items {
+		if item.expired(now) {
+			continue
+		}
+		position := item.point
+		count := 0
+		c.eachInCovering(params.covering(params.searchCap(position, radiusMeters, c.radiusMeters)),
+			func(neighborKey interface{}, neighbor collectionContents) bool {
+				if neighborKey != key &&
+					c.distance(position, neighbor.point) <= radiusMeters {
+					count++
+				}
+				return true
+			})
+		densities = append(densities, NeighborhoodDensity{Item: c.locatedItem(key, item, 0), NeighborCount: count})
+	}
+	sort.Slice(densities, func(i, j int) bool {
+		if densities[i].NeighborCount != densities[j].NeighborCount {
+			return densities[i].NeighborCount > densities[j].NeighborCount
+		}
+		return compareKeys(densities[i].Item.Key, densities[j].Item.Key) < 0
+	})
+	return densities
+}
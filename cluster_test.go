@@ -0,0 +1,86 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_ClusterComponents(t *testing.T) {
+	cl := NewCollection()
+	// a chain of items about 100m apart in Chicago, where the ends are further apart than the gap
+	for i := 0; i < 4; i++ {
+		cl.Set(i, i, cell1.lat+float64(i)*0.0009, cell1.lon)
+	}
+	// a pair of items in Manhattan and one item on its own about 1km away
+	cl.Set(10, 10, cell2.lat, cell2.lon)
+	cl.Set(11, 11, cell2.lat, cell2.lon+0.001)
+	cl.Set(12, 12, cell2.lat+0.009, cell2.lon)
+
+	tests := []struct {
+		name     string
+		gap      float64
+		expected [][]interface{}
+	}{
+		{
+			name:     "Items are grouped through chains of neighbors",
+			gap:      150,
+			expected: [][]interface{}{{0, 1, 2, 3}, {10, 11}, {12}},
+		}, {
+			name:     "Items further apart than the gap are not grouped",
+			gap:      50,
+			expected: [][]interface{}{{0}, {1}, {2}, {3}, {10}, {11}, {12}},
+		}, {
+			name:     "Larger gaps join more items",
+			gap:      1100,
+			expected: [][]interface{}{{0, 1, 2, 3}, {10, 11, 12}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.ClusterComponents(test.gap))
+		})
+	}
+
+	assert.Empty(t, NewCollection().ClusterComponents(100))
+}
+
+func TestCollection_ItemsByNeighborhoodDensity(t *testing.T) {
+	cl := NewCollection()
+	// a cluster of three items about 50m apart in Chicago, a pair in Manhattan and one item on its own
+	cl.Set(0, 0, cell1.lat, cell1.lon)
+	cl.Set(1, 1, cell1.lat+0.0005, cell1.lon)
+	cl.Set(2, 2, cell1.lat, cell1.lon+0.0005)
+	cl.Set(10, 10, cell2.lat, cell2.lon)
+	cl.Set(11, 11, cell2.lat, cell2.lon+0.0005)
+	cl.Set(20, 20, 0, 0)
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 30, LevelMod: 1, MaxCells: 8}
+
+	densities := cl.ItemsByNeighborhoodDensity(100, params)
+	keys := make([]interface{}, 0, len(densities))
+	counts := make([]int, 0, len(densities))
+	for _, density := range densities {
+		keys = append(keys, density.Item.Key)
+		counts = append(counts, density.NeighborCount)
+		assert.Equal(t, density.Item.Key, density.Item.Contents)
+	}
+	assert.Equal(t, []interface{}{0, 1, 2, 10, 11, 20}, keys)
+	assert.Equal(t, []int{2, 2, 2, 1, 1, 0}, counts)
+	assert.Equal(t, cell1.lat, densities[0].Item.Latitude)
+
+	assert.Empty(t, NewCollection().ItemsByNeighborhoodDensity(100, params))
+}
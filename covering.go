@@ -0,0 +1,256 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// collinearTolerance is how close the normals of two consecutive outline edges must be for the edges to be
+// considered part of the same great circle and merged into a single edge
+const collinearTolerance = 1e-12
+
+// coveringResult builds the SearchCoveringResult for a cell union, either as one polygon per cell or, when merge
+// is set, as the outline of the whole union.
+func coveringResult(cellUnion s2.CellUnion, merge bool) SearchCoveringResult {
+	if merge {
+		return mergedCellBounds(cellUnion)
+	}
+	return cellBounds(cellUnion)
+}
+
+// cellBounds returns the boundary of every cell in the union
+func cellBounds(cellUnion s2.CellUnion) SearchCoveringResult {
+	bounds := make(SearchCoveringResult, 0, len(cellUnion))
+	for _, cellID := range cellUnion {
+		// get vertices in counter-clockwise order starting from the lower left
+		cell := s2.CellFromCellID(cellID)
+		vertices := make([]s2.Point, 4)
+		for i := range vertices {
+			vertices[i] = cell.Vertex(i)
+		}
+		bounds = append(bounds, closedLoop(vertices))
+	}
+	return bounds
+}
+
+// mergedCellBounds returns the outline of the area covered by the union as a set of closed loops. Edges shared
+// by two cells in the union are dropped and consecutive edges that lie on the same great circle are joined, so
+// a covering made of many small cells comes back as a single polygon per connected area.
+func mergedCellBounds(cellUnion s2.CellUnion) SearchCoveringResult {
+	union := append(s2.CellUnion(nil), cellUnion...)
+	union.Normalize()
+
+	// next maps the start of every boundary edge to its end. Edges are taken counter-clockwise around each
+	// cell so that following them traces each outline counter-clockwise around the covered area.
+	next := make(map[s2.Point][]s2.Point)
+	starts := make([]s2.Point, 0)
+	addEdge := func(start, end s2.Point) {
+		next[start] = append(next[start], end)
+		starts = append(starts, start)
+	}
+	for _, cellID := range union {
+		neighbors := cellID.EdgeNeighbors()
+		for edge := range neighbors {
+			boundaryEdges(union, cellID, edge, neighbors[edge], addEdge)
+		}
+	}
+
+	bounds := make(SearchCoveringResult, 0)
+	for _, start := range starts {
+		if len(next[start]) == 0 {
+			continue
+		}
+		loop := []s2.Point{start}
+		for vertex := start; ; {
+			ends := next[vertex]
+			if len(ends) == 0 {
+				break
+			}
+			next[vertex] = ends[1:]
+			vertex = ends[0]
+			if vertex == start {
+				break
+			}
+			loop = append(loop, vertex)
+		}
+		bounds = append(bounds, closedLoop(simplifyLoop(loop)))
+	}
+	return bounds
+}
+
+// boundaryEdges reports the parts of the given edge of cellID that lie on the boundary of the union. neighbor is
+// the cell of the same level on the other side of the edge. When the neighbor is only partly covered, the edge is
+// split between the two children of cellID that touch it and each half is checked on its own.
+func boundaryEdges(union s2.CellUnion, cellID s2.CellID, edge int, neighbor s2.CellID, addEdge func(start, end s2.Point)) {
+	if union.ContainsCellID(neighbor) {
+		return
+	}
+	if !union.IntersectsCellID(neighbor) || cellID.IsLeaf() {
+		cell := s2.CellFromCellID(cellID)
+		addEdge(cell.Vertex(edge), cell.Vertex((edge+1)%4))
+		return
+	}
+	for _, child := range cellID.Children() {
+		childNeighbor := child.EdgeNeighbors()[edge]
+		if childNeighbor.Parent(cellID.Level()) == cellID {
+			// this child does not touch the edge
+			continue
+		}
+		boundaryEdges(union, child, edge, childNeighbor, addEdge)
+	}
+}
+
+// simplifyLoop drops vertices that sit in the middle of a straight run of edges
+func simplifyLoop(loop []s2.Point) []s2.Point {
+	if len(loop) <= 3 {
+		return loop
+	}
+	simplified := make([]s2.Point, 0, len(loop))
+	for i, vertex := range loop {
+		prev := loop[(i+len(loop)-1)%len(loop)]
+		next := loop[(i+1)%len(loop)]
+		incoming := prev.Cross(vertex.Vector).Normalize()
+		outgoing := vertex.Cross(next.Vector).Normalize()
+		if incoming.Dot(outgoing) > 1-collinearTolerance {
+			continue
+		}
+		simplified = append(simplified, vertex)
+	}
+	return simplified
+}
+
+// closedLoop converts a loop of points into lng/lat pairs, repeating the first vertex at the end to close it
+func closedLoop(loop []s2.Point) [][]float64 {
+	vertices := make([][]float64, 0, len(loop)+1)
+	for _, vertex := range loop {
+		ll := s2.LatLngFromPoint(vertex)
+		vertices = append(vertices, []float64{ll.Lng.Degrees(), ll.Lat.Degrees()})
+	}
+	if len(vertices) > 0 {
+		vertices = append(vertices, vertices[0])
+	}
+	return vertices
+}
+
+const (
+	// defaultLevelSpan is how many levels coarser than its maximum level DefaultSearchParameters lets the coverer go
+	defaultLevelSpan = 4
+	// defaultMaxCells is the MaxCells of the parameters returned by DefaultSearchParameters
+	defaultMaxCells = 16
+)
+
+// DefaultSearchParameters returns covering parameters suited to searching distanceMeters around a point on the
+// Earth. The maximum level is the finest level whose cells are at least a quarter of the radius wide, which
+// keeps the covering close to the search area without needing more cells than a search can cheaply look up, and
+// the minimum level is up to four levels coarser so the coverer can use large cells in the middle of the area.
+// For example, a 100m search gets levels 13 to 17, a 1km search levels 10 to 14 and a 100km search levels 3 to
+// 7. Negative or NaN distances are treated as zero, which searches the leaf cells around the point.
+func DefaultSearchParameters(distanceMeters float64) SearchCoveringParameters {
+	if !(distanceMeters > 0) {
+		distanceMeters = 0
+	}
+	maxLevel := s2.MinWidthMetric.MaxLevel(distanceMeters / EarthRadiusMeters / 4)
+	return SearchCoveringParameters{
+		MinLevel: max(maxLevel-defaultLevelSpan, 0),
+		MaxLevel: maxLevel,
+		LevelMod: 1,
+		MaxCells: defaultMaxCells,
+	}
+}
+
+// maxAutoMaxCells bounds the number of cells AutoMaxCells may request from the coverer. Past this point the
+// covering is already within a couple percent of the region's area and more cells only slow the search down.
+const maxAutoMaxCells = 1000
+
+// Validate checks that the parameters describe a covering the coverer can produce as given, returning an error
+// wrapping ErrInvalidCoveringParams that describes the first problem found. The coverer silently adjusts invalid
+// parameters, which makes searches with them return surprising results.
+func (p SearchCoveringParameters) Validate() error {
+	if p.MinLevel < 0 || p.MinLevel > maxCellLevel {
+		return fmt.Errorf("%w: min level %d is outside of [0, %d]", ErrInvalidCoveringParams, p.MinLevel, maxCellLevel)
+	}
+	if p.MaxLevel < 0 || p.MaxLevel > maxCellLevel {
+		return fmt.Errorf("%w: max level %d is outside of [0, %d]", ErrInvalidCoveringParams, p.MaxLevel, maxCellLevel)
+	}
+	if p.MinLevel > p.MaxLevel {
+		return fmt.Errorf(
+			"%w: min level %d is greater than max level %d", ErrInvalidCoveringParams, p.MinLevel, p.MaxLevel)
+	}
+	if p.LevelMod < 1 || p.LevelMod > 3 {
+		return fmt.Errorf("%w: level mod %d is not 1, 2 or 3", ErrInvalidCoveringParams, p.LevelMod)
+	}
+	if p.MaxCells <= 0 {
+		return fmt.Errorf("%w: max cells %d is not positive", ErrInvalidCoveringParams, p.MaxCells)
+	}
+	return nil
+}
+
+// regionCoverer returns the coverer described by the parameters for covering region
+func (p SearchCoveringParameters) regionCoverer(region s2.Region) *s2.RegionCoverer {
+	coverer := &s2.RegionCoverer{
+		MaxLevel: p.MaxLevel,
+		MinLevel: p.MinLevel,
+		LevelMod: p.LevelMod,
+		MaxCells: p.MaxCells,
+	}
+	if p.AutoMaxCells {
+		coverer.MaxCells = autoMaxCells(p.MaxCells, p.MaxLevel, region.CapBound().Area())
+	}
+	return coverer
+}
+
+// covering computes the cell covering of region according to the parameters. If the coverer returns no cells
+// for a region that is not empty, the covering falls back to cells bounding the region, see coveringOrFallback.
+func (p SearchCoveringParameters) covering(region s2.Region) s2.CellUnion {
+	coverer := p.regionCoverer(region)
+	if p.UseFastCovering {
+		return coveringOrFallback(coverer.FastCovering(region), region)
+	}
+	return coveringOrFallback(coverer.Covering(region), region)
+}
+
+// coveringOrFallback returns cellUnion unless it is empty while region is not, in which case it returns the cells
+// of the region's own cell union bound or, if that is empty too, those bounding its cap. This way a search with
+// parameters that the coverer cannot satisfy still finds every item in the region, along with some outside of it,
+// rather than silently finding nothing.
+func coveringOrFallback(cellUnion s2.CellUnion, region s2.Region) s2.CellUnion {
+	if len(cellUnion) > 0 {
+		return cellUnion
+	}
+	bound := region.CapBound()
+	if bound.IsEmpty() {
+		return cellUnion
+	}
+	if cellIDs := region.CellUnionBound(); len(cellIDs) > 0 {
+		return cellIDs
+	}
+	return bound.CellUnionBound()
+}
+
+// autoMaxCells scales maxCells by the width of the search area measured in cells of maxLevel, i.e. the square root
+// of the number of maxLevel cells it would take to tile an area of areaSteradians. A search no wider than a single
+// maxLevel cell keeps maxCells as-is, while wider searches get proportionally more cells so that the covering stays
+// roughly as tight, relative to the search area, as the coverer is able to make small searches. The result is
+// limited to maxAutoMaxCells.
+func autoMaxCells(maxCells, maxLevel int, areaSteradians float64) int {
+	maxCells = max(maxCells, 1)
+	cellArea := s2.AvgAreaMetric.Value(min(max(maxLevel, 0), maxCellLevel))
+	scale := math.Max(math.Ceil(math.Sqrt(areaSteradians/cellArea)), 1)
+	return int(math.Min(float64(maxCells)*scale, maxAutoMaxCells))
+}
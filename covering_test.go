@@ -0,0 +1,250 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopFromBounds converts a closed lng/lat ring from a SearchCoveringResult back into an s2 loop
+func loopFromBounds(ring [][]float64) *s2.Loop {
+	points := make([]s2.Point, 0, len(ring)-1)
+	for _, vertex := range ring[:len(ring)-1] {
+		points = append(points, NewPointFromLatLng(vertex[1], vertex[0]))
+	}
+	return s2.LoopFromPoints(points)
+}
+
+func TestCoveringResult(t *testing.T) {
+	searchCap := s2.CapFromCenterAngle(NewPointFromLatLng(cell1.lat, cell1.lon), s1.Angle(5000/EarthRadiusMeters))
+	tests := []struct {
+		name     string
+		coverer  s2.RegionCoverer
+		numLoops int
+	}{
+		{
+			name:     "Cells of a single level are merged into one outline",
+			coverer:  s2.RegionCoverer{MinLevel: 14, MaxLevel: 14, LevelMod: 1, MaxCells: 100},
+			numLoops: 1,
+		}, {
+			name:     "Cells of mixed levels are merged into one outline",
+			coverer:  s2.RegionCoverer{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 50},
+			numLoops: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cellUnion := test.coverer.Covering(searchCap)
+			perCell := coveringResult(cellUnion, false)
+			assert.Len(t, perCell, len(cellUnion))
+
+			merged := coveringResult(cellUnion, true)
+			require.Len(t, merged, test.numLoops)
+			assert.Less(t, len(merged[0]), len(perCell)*5)
+			outline := loopFromBounds(merged[0])
+			require.NoError(t, outline.Validate())
+			assert.InEpsilon(t, cellUnion.ExactArea(), outline.Area(), 1e-6)
+		})
+	}
+}
+
+func TestCoveringResult_disjoint(t *testing.T) {
+	// two cells from different cities are not adjacent, so each keeps its own outline
+	cellUnion := s2.CellUnion{cell1.cellID.Parent(12), cell2.cellID.Parent(12)}
+	cellUnion.Normalize()
+	merged := coveringResult(cellUnion, true)
+	require.Len(t, merged, 2)
+	for i, ring := range merged {
+		assert.Len(t, ring, 5)
+		assert.InEpsilon(t, s2.CellFromCellID(cellUnion[i]).ExactArea(), loopFromBounds(ring).Area(), 1e-6)
+	}
+}
+
+func TestSearchCoveringParameters_AutoMaxCells(t *testing.T) {
+	// precision is the area of the covering relative to the area of the search cap
+	precision := func(radiusMeters float64, params SearchCoveringParameters) float64 {
+		searchCap := s2.CapFromCenterAngle(NewPointFromLatLng(cell1.lat, cell1.lon), s1.Angle(radiusMeters/EarthRadiusMeters))
+		cellUnion := params.covering(searchCap)
+		return cellUnion.ApproxArea() / searchCap.Area()
+	}
+	static := SearchCoveringParameters{MinLevel: 0, MaxLevel: 16, LevelMod: 1, MaxCells: 5}
+	auto := static
+	auto.AutoMaxCells = true
+
+	small, large := precision(2000, auto), precision(50000, auto)
+	assert.Less(t, small, 1.2)
+	assert.Less(t, large, 1.2)
+	assert.InDelta(t, small, large, 0.1)
+	// without scaling the same parameters cover well over twice the requested area
+	assert.Greater(t, precision(2000, static), 2.0)
+	assert.Greater(t, precision(50000, static), 2.0)
+}
+
+func TestDefaultSearchParameters(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	tests := []struct {
+		name               string
+		distanceMeters     float64
+		minLevel, maxLevel int
+	}{
+		{"zero distance", 0, 26, 30},
+		{"negative distance", -1, 26, 30},
+		{"NaN distance", math.NaN(), 26, 30},
+		{"100 meters", 100, 13, 17},
+		{"1 kilometer", 1000, 10, 14},
+		{"100 kilometers", 100000, 3, 7},
+		{"larger than the earth", 1e8, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := DefaultSearchParameters(test.distanceMeters)
+			assert.Equal(t, SearchCoveringParameters{
+				MinLevel: test.minLevel, MaxLevel: test.maxLevel, LevelMod: 1, MaxCells: defaultMaxCells}, params)
+			results, covering := cl.ItemsWithinDistance(cell1.lat, cell1.lon, test.distanceMeters, params)
+			if test.distanceMeters >= 0 {
+				assert.Equal(t, []interface{}{"0"}, results)
+				assert.LessOrEqual(t, len(covering), defaultMaxCells)
+			}
+		})
+	}
+}
+
+func TestSearchCoveringParameters_Validate(t *testing.T) {
+	valid := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+	tests := []struct {
+		name        string
+		modify      func(params *SearchCoveringParameters)
+		expectedErr bool
+	}{
+		{"valid parameters", func(*SearchCoveringParameters) {}, false},
+		{"single level", func(p *SearchCoveringParameters) { p.MinLevel, p.MaxLevel = 30, 30 }, false},
+		{"level mod of three", func(p *SearchCoveringParameters) { p.LevelMod = 3 }, false},
+		{"negative min level", func(p *SearchCoveringParameters) { p.MinLevel = -1 }, true},
+		{"max level past the leaves", func(p *SearchCoveringParameters) { p.MaxLevel = 31 }, true},
+		{"min level past the leaves", func(p *SearchCoveringParameters) { p.MinLevel = 31 }, true},
+		{"negative max level", func(p *SearchCoveringParameters) { p.MaxLevel = -1 }, true},
+		{"min level greater than max level", func(p *SearchCoveringParameters) { p.MinLevel = 17 }, true},
+		{"zero level mod", func(p *SearchCoveringParameters) { p.LevelMod = 0 }, true},
+		{"level mod of four", func(p *SearchCoveringParameters) { p.LevelMod = 4 }, true},
+		{"zero max cells", func(p *SearchCoveringParameters) { p.MaxCells = 0 }, true},
+		{"negative max cells", func(p *SearchCoveringParameters) { p.MaxCells = -8 }, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := valid
+			test.modify(&params)
+			err := params.Validate()
+			if test.expectedErr {
+				assert.ErrorIs(t, err, ErrInvalidCoveringParams)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestAutoMaxCells(t *testing.T) {
+	cellArea := s2.AvgAreaMetric.Value(16)
+	tests := []struct {
+		name     string
+		maxCells int
+		area     float64
+		expected int
+	}{
+		{name: "Areas smaller than a cell keep MaxCells", maxCells: 5, area: cellArea / 2, expected: 5},
+		{name: "MaxCells scales with the width of the area", maxCells: 5, area: cellArea * 100, expected: 50},
+		{name: "MaxCells is capped", maxCells: 5, area: cellArea * 1e6, expected: maxAutoMaxCells},
+		{name: "Unset MaxCells still scales", maxCells: 0, area: cellArea * 16, expected: 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, autoMaxCells(test.maxCells, 16, test.area))
+		})
+	}
+}
+
+func TestCoveringOrFallback(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	pointCap := SearchCoveringParameters{}.searchCap(NewPointFromLatLng(cell1.lat, cell1.lon), 0, cl.radiusMeters)
+	tests := []struct {
+		name      string
+		cellUnion s2.CellUnion
+		region    s2.Region
+		expected  []interface{}
+	}{
+		{
+			name:      "Coverings with cells are used as-is",
+			cellUnion: s2.CellUnion{cell2.cellID.Parent(10)},
+			region:    pointCap,
+			expected:  []interface{}{},
+		}, {
+			name:     "Empty coverings of a region fall back to cells bounding it",
+			region:   pointCap,
+			expected: []interface{}{"0"},
+		}, {
+			name:     "Empty coverings of an empty region stay empty",
+			region:   s2.EmptyCap(),
+			expected: []interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cellUnion := coveringOrFallback(test.cellUnion, test.region)
+			assert.Equal(t, test.expected, cl.itemsInCovering(cellUnion))
+		})
+	}
+
+	// the coverer finds cells for any cap whatever the parameters, so a region it finds no cells in stands in for
+	// parameters that it cannot satisfy. The fallback finds items away from the center of the region too.
+	center := NewPointFromLatLng(cell1.lat, cell1.lon)
+	region := uncoverableRegion{SearchCoveringParameters{}.searchCap(center, 1000, cl.radiusMeters)}
+	// an item roughly 800m north of the center
+	cl.Set(1, "1", cell1.lat+0.0072, cell1.lon)
+	for _, fast := range []bool{false, true} {
+		params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8, UseFastCovering: fast}
+		require.Empty(t, params.regionCoverer(region).Covering(region))
+		cellUnion := params.covering(region)
+		assert.Equal(t, region.CapBound().CellUnionBound(), []s2.CellID(cellUnion), "fast covering %t", fast)
+		assert.ElementsMatch(t, []interface{}{"0", "1"}, cl.itemsInCovering(cellUnion), "fast covering %t", fast)
+	}
+}
+
+// uncoverableRegion is a region that the coverer finds no cells in, although its bounding cap is not empty
+type uncoverableRegion struct {
+	s2.Cap
+}
+
+// IntersectsCell implements s2.Region
+func (uncoverableRegion) IntersectsCell(s2.Cell) bool {
+	return false
+}
+
+// ContainsCell implements s2.Region
+func (uncoverableRegion) ContainsCell(s2.Cell) bool {
+	return false
+}
+
+// CellUnionBound implements s2.Region
+func (uncoverableRegion) CellUnionBound() []s2.CellID {
+	return nil
+}
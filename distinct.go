@@ -0,0 +1,49 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"reflect"
+)
+
+// distinctContents drops the items whose contents are equal to those of an earlier item, keeping the first of
+// each set of duplicates. Contents are compared with ==, so contents whose values are not comparable, such as
+// slices, maps or structs holding them, are never considered duplicates and are always kept.
+func distinctContents(items []LocatedItem) []LocatedItem {
+	seen := make(seenContents, len(items))
+	distinct := items[:0]
+	for _, item := range items {
+		if seen.add(item.Contents) {
+			distinct = append(distinct, item)
+		}
+	}
+	return distinct
+}
+
+// seenContents is the set of contents found so far by a search that drops duplicate contents
+type seenContents map[interface{}]bool
+
+// add adds contents to the set and reports whether they were not in it yet. Contents that are not comparable are
+// never added and are always reported as new, as in distinctContents.
+func (s seenContents) add(contents interface{}) bool {
+	if contents != nil && !reflect.ValueOf(contents).Comparable() {
+		return true
+	}
+	if s[contents] {
+		return false
+	}
+	s[contents] = true
+	return true
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistinctContents(t *testing.T) {
+	type place struct {
+		name string
+	}
+	type tagged struct {
+		tags []string
+	}
+	tests := []struct {
+		name     string
+		items    []LocatedItem
+		expected []interface{}
+	}{
+		{
+			"equal contents are collapsed into the first",
+			[]LocatedItem{{Key: 0, Contents: "a"}, {Key: 1, Contents: "b"}, {Key: 2, Contents: "a"}},
+			[]interface{}{0, 1},
+		}, {
+			"comparable structs are compared by value",
+			[]LocatedItem{{Key: 0, Contents: place{"a"}}, {Key: 1, Contents: place{"a"}}, {Key: 2, Contents: place{"b"}}},
+			[]interface{}{0, 2},
+		}, {
+			"equal values of different types are distinct",
+			[]LocatedItem{{Key: 0, Contents: 1}, {Key: 1, Contents: int64(1)}},
+			[]interface{}{0, 1},
+		}, {
+			"nil contents are collapsed",
+			[]LocatedItem{{Key: 0, Contents: nil}, {Key: 1, Contents: nil}},
+			[]interface{}{0},
+		}, {
+			"contents that are not comparable are kept",
+			[]LocatedItem{
+				{Key: 0, Contents: []string{"a"}},
+				{Key: 1, Contents: []string{"a"}},
+				{Key: 2, Contents: tagged{[]string{"a"}}},
+				{Key: 3, Contents: tagged{[]string{"a"}}},
+			},
+			[]interface{}{0, 1, 2, 3},
+		}, {
+			"no items",
+			[]LocatedItem{},
+			[]interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys := make([]interface{}, 0)
+			for _, item := range distinctContents(test.items) {
+				keys = append(keys, item.Key)
+			}
+			assert.Equal(t, test.expected, keys)
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistance_distinctContents(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "a", cell1.lat+0.001, cell1.lon)
+	cl.Set(1, "a", cell1.lat, cell1.lon)
+	cl.Set(2, "b", cell1.lat+0.002, cell1.lon)
+	cl.Set(3, []string{"c"}, cell1.lat, cell1.lon)
+	cl.Set(4, []string{"c"}, cell1.lat, cell1.lon)
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Len(t, found, 5)
+
+	params.DistinctContents = true
+	found, _ = cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.ElementsMatch(t, []interface{}{"a", "b", []string{"c"}, []string{"c"}}, found)
+
+	params.SortByDistance = true
+	found, _ = cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Equal(t, []interface{}{"a", []string{"c"}, []string{"c"}, "b"}, found)
+	assert.Equal(t, found, cl.FrozenSnapshot().ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000, params))
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+const (
+	// wgs84SemiMajorAxisMeters is the equatorial radius of the WGS84 ellipsoid
+	wgs84SemiMajorAxisMeters = 6378137.0
+	// wgs84Flattening is the flattening of the WGS84 ellipsoid
+	wgs84Flattening = 1 / 298.257223563
+	// vincentyTolerance is the change in longitude on the auxiliary sphere, in radians, below which Vincenty's
+	// iteration is considered converged. It corresponds to well under a millimeter on the ground.
+	vincentyTolerance = 1e-12
+	// vincentyMaxIterations bounds Vincenty's iteration, which converges in a handful of steps except for nearly
+	// antipodal points, where it may not converge at all
+	vincentyMaxIterations = 200
+)
+
+// EarthDistanceMetersEllipsoid calculates the distance in meters between two points on the surface of the WGS84
+// ellipsoid using Vincenty's inverse formula, treating the latitude of each point as a geodetic latitude. It is
+// accurate to well under a millimeter, whereas EarthDistanceMeters, which measures on a sphere, can be off by
+// about half a percent. It is slower than EarthDistanceMeters, which remains the distance used by
+// searches. For nearly antipodal points, where Vincenty's formula does not converge, the spherical distance from
+// EarthDistanceMeters is returned instead.
+func EarthDistanceMetersEllipsoid(p1, p2 s2.Point) float64 {
+	ll1, ll2 := s2.LatLngFromPoint(p1), s2.LatLngFromPoint(p2)
+	const semiMinorAxis = wgs84SemiMajorAxisMeters * (1 - wgs84Flattening)
+
+	// reduced latitudes, i.e. latitudes on the auxiliary sphere
+	u1 := math.Atan((1 - wgs84Flattening) * math.Tan(ll1.Lat.Radians()))
+	u2 := math.Atan((1 - wgs84Flattening) * math.Tan(ll2.Lat.Radians()))
+	sinU1, cosU1 := math.Sincos(u1)
+	sinU2, cosU2 := math.Sincos(u2)
+	l := ll2.Lng.Radians() - ll1.Lng.Radians()
+
+	lambda := l
+	for i := 0; i < vincentyMaxIterations; i++ {
+		sinLambda, cosLambda := math.Sincos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			// the points coincide
+			return 0
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cosSqAlpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cosSqAlpha != 0 {
+			// cosSqAlpha is zero for points on the equator, where the geodesic is the equator itself
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cosSqAlpha
+		}
+		c := wgs84Flattening / 16 * cosSqAlpha * (4 + wgs84Flattening*(4-3*cosSqAlpha))
+		previous := lambda
+		lambda = l + (1-c)*wgs84Flattening*sinAlpha*
+			(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-previous) > vincentyTolerance {
+			continue
+		}
+
+		uSq := cosSqAlpha * (wgs84SemiMajorAxisMeters*wgs84SemiMajorAxisMeters - semiMinorAxis*semiMinorAxis) /
+			(semiMinorAxis * semiMinorAxis)
+		a := 1 + uSq/16384*(4096+uSq*(-768+uSq*(320-175*uSq)))
+		b := uSq / 1024 * (256 + uSq*(-128+uSq*(74-47*uSq)))
+		deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		return semiMinorAxis * a * (sigma - deltaSigma)
+	}
+	return EarthDistanceMeters(p1, p2)
+}
@@ -0,0 +1,51 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEarthDistanceMetersEllipsoid(t *testing.T) {
+	// expected distances are geodesics on the WGS84 ellipsoid as computed by GeographicLib, except for the
+	// Flinders Peak to Buninyong line, which is the example from Vincenty's paper on the Australian National
+	// Spheroid, whose ellipsoid differs from WGS84 by well under a millimeter over this distance
+	tests := []struct {
+		name           string
+		lat1, lon1     float64
+		lat2, lon2     float64
+		expectedMeters float64
+	}{
+		{"same point", cell1.lat, cell1.lon, cell1.lat, cell1.lon, 0},
+		{"flinders peak to buninyong", -37.95103342, 144.42486789, -37.65282114, 143.92649554, 54972.271},
+		{"new york to london", 40.6, -73.8, 51.6, -0.5, 5551759.400},
+		{"equator to the north pole", 0, 0, 90, 0, 10001965.729},
+		{"quarter of the equator", 0, 0, 0, 90, 10018754.171},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p1, p2 := NewPointFromLatLng(test.lat1, test.lon1), NewPointFromLatLng(test.lat2, test.lon2)
+			assert.InDelta(t, test.expectedMeters, EarthDistanceMetersEllipsoid(p1, p2), 0.01)
+			assert.InDelta(t, test.expectedMeters, EarthDistanceMetersEllipsoid(p2, p1), 0.01)
+		})
+	}
+
+	t.Run("nearly antipodal points fall back to the sphere", func(t *testing.T) {
+		p1, p2 := NewPointFromLatLng(0, 0), NewPointFromLatLng(0.5, 179.7)
+		assert.Equal(t, EarthDistanceMeters(p1, p2), EarthDistanceMetersEllipsoid(p1, p2))
+	})
+}
@@ -0,0 +1,39 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import "errors"
+
+// Errors returned by the collection. They may be wrapped with additional context, so callers should match them
+// with errors.Is rather than by equality.
+var (
+	// ErrKeyNotFound is returned by operations on a single key, such as Remove, when the key is not stored in
+	// the collection.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrInvalidCoordinate is returned by methods that validate their input when a latitude or longitude is out
+	// of range.
+	ErrInvalidCoordinate = errors.New("invalid coordinate")
+	// ErrInvalidDistance is returned by methods that validate their input when a search distance is negative.
+	ErrInvalidDistance = errors.New("invalid distance")
+	// ErrInvalidCoveringParams is returned by methods that validate their input when the SearchCoveringParameters
+	// cannot produce a meaningful covering.
+	ErrInvalidCoveringParams = errors.New("invalid covering parameters")
+	// ErrInvalidPolygon is returned by polygon searches when the polygon is not a valid ring, such as when it has
+	// fewer than three distinct vertices or crosses itself.
+	ErrInvalidPolygon = errors.New("invalid polygon")
+	// ErrInvalidGeoJSON is returned when loading GeoJSON that is not a FeatureCollection, or that has features
+	// the loader cannot store.
+	ErrInvalidGeoJSON = errors.New("invalid GeoJSON")
+)
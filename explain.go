@@ -0,0 +1,90 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// Explain returns a human-readable report of how the item stored for key is indexed, for debugging why a search
+// does or does not find it. The report lists the item's stored coordinates, the cell it is indexed from, and
+// every cell of the index it occupies, and flags each place where the index differs from what indexing the item
+// again from its coordinates would produce. The format of the report is meant for people and may change.
+func (c Collection) Explain(key interface{}) string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var report strings.Builder
+	item, ok := c.items[key]
+	if !ok {
+		fmt.Fprintf(&report, "key %v is not stored\n", key)
+		return report.String()
+	}
+
+	level := maxCellLevel
+	if item.cellID.IsValid() {
+		level = item.cellID.Level()
+	}
+	expected := s2.CellIDFromLatLng(s2.LatLngFromDegrees(item.latitude, item.longitude)).Parent(level)
+	problems := 0
+	flag := func(format string, args ...interface{}) {
+		problems++
+		fmt.Fprintf(&report, "  INCONSISTENT: "+format+"\n", args...)
+	}
+
+	fmt.Fprintf(&report, "key %v\n", key)
+	fmt.Fprintf(&report, "coordinates: %v, %v\n", item.latitude, item.longitude)
+	fmt.Fprintf(&report, "cell: %s (id %d, level %d)\n", item.cellID.ToToken(), uint64(item.cellID), item.cellID.Level())
+	if item.cellID != expected {
+		flag("the coordinates are in cell %s", expected.ToToken())
+	}
+
+	entries := append([]itemIndex(nil), c.keys[key]...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].cellLevel < entries[j].cellLevel })
+	fmt.Fprintf(&report, "index entries: %d\n", len(entries))
+	seen := make(map[int]bool, len(entries))
+	for _, entry := range entries {
+		fmt.Fprintf(&report, "  level %2d: %s\n", entry.cellLevel, entry.cellID.ToToken())
+		switch {
+		case seen[entry.cellLevel]:
+			flag("level %d is indexed more than once", entry.cellLevel)
+		case entry.cellLevel > level:
+			flag("level %d is finer than the item's cell", entry.cellLevel)
+		case !c.isIndexedLevel(entry.cellLevel):
+			flag("level %d is not one of the indexed levels %d to %d", entry.cellLevel, c.minIndexLevel, c.maxIndexLevel)
+		case entry.cellID != expected.Parent(entry.cellLevel):
+			flag("level %d should be cell %s", entry.cellLevel, expected.Parent(entry.cellLevel).ToToken())
+		}
+		seen[entry.cellLevel] = true
+		if !c.cells[entry.cellLevel][entry.cellID][key] {
+			flag("cell %s at level %d does not hold the key", entry.cellID.ToToken(), entry.cellLevel)
+		}
+	}
+	for l := c.minIndexLevel; l <= min(level, c.maxIndexLevel); l++ {
+		if !seen[l] {
+			flag("level %d is missing, it should be cell %s", l, expected.Parent(l).ToToken())
+		}
+	}
+
+	if problems == 0 {
+		report.WriteString("index is consistent\n")
+	} else {
+		fmt.Fprintf(&report, "%d inconsistencies found, ReindexKey rebuilds the item's index entries\n", problems)
+	}
+	return report.String()
+}
@@ -0,0 +1,58 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollection_Explain(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	require.NoError(t, cl.SetToken(1, "1", cell1.cellID.Parent(12).ToToken()))
+
+	report := cl.Explain(0)
+	assert.Contains(t, report, cell1.cellID.ToToken())
+	assert.Contains(t, report, "index entries: 31")
+	assert.Contains(t, report, "index is consistent")
+	assert.NotContains(t, report, "INCONSISTENT")
+
+	report = cl.Explain(1)
+	assert.Contains(t, report, "index entries: 13")
+	assert.Contains(t, report, "index is consistent")
+
+	assert.Equal(t, "key 2 is not stored\n", cl.Explain(2))
+
+	// point the level 12 entry of the first item at a cell in another city
+	wrongCell := cell2.cellID.Parent(12)
+	delete(cl.cells[12][cell1.cellID.Parent(12)], 0)
+	for i, entry := range cl.keys[0] {
+		if entry.cellLevel == 12 {
+			cl.keys[0][i].cellID = wrongCell
+		}
+	}
+	report = cl.Explain(0)
+	assert.Contains(t, report, "INCONSISTENT: level 12 should be cell "+cell1.cellID.Parent(12).ToToken())
+	assert.Contains(t, report, "INCONSISTENT: cell "+wrongCell.ToToken()+" at level 12 does not hold the key")
+	assert.Equal(t, 2, strings.Count(report, "INCONSISTENT"))
+	assert.Contains(t, report, "2 inconsistencies found")
+
+	require.True(t, cl.ReindexKey(0))
+	assert.Contains(t, cl.Explain(0), "index is consistent")
+}
@@ -0,0 +1,176 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sort"
+
+	"github.com/golang/geo/s2"
+	"github.com/samber/lo"
+)
+
+// FrozenCollection is an immutable, point-in-time copy of a Collection. Since it cannot change, it needs no lock
+// and may be shared by any number of goroutines. Instead of a map per level, it indexes items in a single sorted
+// slice of cells, which is smaller and faster to search than the index of a Collection.
+type FrozenCollection struct {
+	items        map[interface{}]collectionContents
+	index        sortedLeafIndex
+	copyContents func(interface{}) interface{}
+	radiusMeters float64
+	// keys holds the keys of the items ordered by key, for paging through them
+	keys []interface{}
+}
+
+var _ Reader = FrozenCollection{}
+
+// FrozenSnapshot returns an immutable copy of the collection as it is now. The copy shares nothing mutable with
+// the collection, so later changes to the collection do not affect it and it can be garbage collected
+// independently. Building the copy holds the read lock for time proportional to the size of the collection. Items
+// that have expired are left out of the copy, but a frozen collection has no clock, so items that expire later
+// stay in it.
+func (c Collection) FrozenSnapshot() FrozenCollection {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := c.now()
+	items := make(map[interface{}]collectionContents, len(c.items))
+	for key, item := range c.items {
+		if !item.expired(now) {
+			items[key] = item
+		}
+	}
+	keys := make([]interface{}, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+	return FrozenCollection{
+		items:        items,
+		index:        buildSortedLeafIndex(items),
+		keys:         keys,
+		copyContents: c.copyContents,
+		radiusMeters: c.radiusMeters,
+	}
+}
+
+// ItemsWithinDistance returns all contents within distanceMeters of the given latitude and longitude along with
+// the covering used for the search, in the same way as Collection.ItemsWithinDistance.
+func (f FrozenCollection) ItemsWithinDistance(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) ([]interface{}, SearchCoveringResult) {
+	if validateSearch(latitude, longitude, distanceMeters) != nil {
+		return []interface{}{}, SearchCoveringResult{}
+	}
+	center := NewPointFromLatLng(latitude, longitude)
+	cellUnion := params.covering(params.searchCap(center, distanceMeters, f.radiusMeters))
+	return f.itemsNear(center, cellUnion, params), coveringResult(cellUnion, params.MergeCovering)
+}
+
+// ItemsWithinDistanceOnly performs the same search as ItemsWithinDistance but returns only the items.
+func (f FrozenCollection) ItemsWithinDistanceOnly(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) []interface{} {
+	if validateSearch(latitude, longitude, distanceMeters) != nil {
+		return []interface{}{}
+	}
+	center := NewPointFromLatLng(latitude, longitude)
+	cellUnion := params.covering(params.searchCap(center, distanceMeters, f.radiusMeters))
+	return f.itemsNear(center, cellUnion, params)
+}
+
+// itemsNear returns the contents of every item indexed in the cells of the covering, ordered by their distance from
+// center and then by key when SortByDistance is set and with duplicates collapsed when DistinctContents is set
+func (f FrozenCollection) itemsNear(
+	center s2.Point, cellUnion s2.CellUnion, params SearchCoveringParameters,
+) []interface{} {
+	if !params.SortByDistance && !params.DistinctContents {
+		return f.itemsInCovering(cellUnion)
+	}
+	found := make([]LocatedItem, 0)
+	for _, cell := range cellUnion {
+		for _, key := range f.index.keysInCell(cell) {
+			item := f.items[key]
+			found = append(found, LocatedItem{Key: key, Contents: item.contents})
+			if params.SortByDistance {
+				found[len(found)-1].DistanceMeters = f.distance(center, item.point)
+			}
+		}
+	}
+	if params.SortByDistance {
+		sortLocatedItems(found)
+	}
+	if params.DistinctContents {
+		found = distinctContents(found)
+	}
+	foundItems := make([]interface{}, 0, len(found))
+	for _, item := range found {
+		foundItems = append(foundItems, f.copied(item.Contents))
+	}
+	return foundItems
+}
+
+// itemsInCovering returns the contents of every item indexed in the cells of the covering
+func (f FrozenCollection) itemsInCovering(cellUnion s2.CellUnion) []interface{} {
+	foundItems := make([]interface{}, 0)
+	for _, cell := range cellUnion {
+		for _, key := range f.index.keysInCell(cell) {
+			foundItems = append(foundItems, f.copied(f.items[key].contents))
+		}
+	}
+	return foundItems
+}
+
+// ItemByKey returns the contents stored by key, or nil if the key is not stored
+func (f FrozenCollection) ItemByKey(key interface{}) interface{} {
+	item, ok := f.items[key]
+	if !ok {
+		return nil
+	}
+	return f.copied(item.contents)
+}
+
+// GetItems returns a page of the contents in the collection, ordered by key as in Collection.GetItems
+func (f FrozenCollection) GetItems(pageSize, startIndex int) []interface{} {
+	keys := lo.Slice(f.keys, startIndex, startIndex+pageSize)
+	r := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		r = append(r, f.copied(f.items[key].contents))
+	}
+	return r
+}
+
+// Len returns the number of items in the collection
+func (f FrozenCollection) Len() int {
+	return len(f.items)
+}
+
+// copied returns a copy of contents made with the contents copier of the collection the snapshot was taken
+// from, if there is one
+func (f FrozenCollection) copied(contents interface{}) interface{} {
+	if f.copyContents == nil {
+		return contents
+	}
+	return f.copyContents(contents)
+}
+
+// Radius returns the radius in meters of the sphere that the snapshot measures distances on, which is that of the
+// collection it was taken from
+func (f FrozenCollection) Radius() float64 {
+	return f.radiusMeters
+}
+
+// distance calculates the distance in meters between two points on the surface of the snapshot's sphere
+func (f FrozenCollection) distance(p1, p2 s2.Point) float64 {
+	return float64(p1.Distance(p2)) * f.radiusMeters
+}
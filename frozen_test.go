@@ -0,0 +1,55 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_FrozenSnapshot(t *testing.T) {
+	cl, _ := randomCollection(200)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	expected := cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 5000, params)
+	page := cl.GetItems(10, 5)
+	frozen := cl.FrozenSnapshot()
+	// pages are ordered by key as in the collection
+	assert.Equal(t, page, frozen.GetItems(10, 5))
+
+	// changes to the collection do not affect the snapshot
+	cl.Delete(0)
+	cl.Set(1000, 1000, cell1.lat, cell1.lon)
+	assert.Equal(t, 200, frozen.Len())
+	assert.Equal(t, 0, frozen.ItemByKey(0))
+	assert.Nil(t, frozen.ItemByKey(1000))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, _ := frozen.ItemsWithinDistance(cell1.lat, cell1.lon, 5000, params)
+			assert.ElementsMatch(t, expected, found)
+			assert.ElementsMatch(t, expected, frozen.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 5000, params))
+			assert.Len(t, frozen.GetItems(1000, 0), 200)
+			assert.Equal(t, frozen.GetItems(10, 5), frozen.GetItems(10, 5))
+			// writes to the parent collection run alongside reads of the snapshot
+			cl.Set(2000+i, i, cell2.lat, cell2.lon)
+		}()
+	}
+	wg.Wait()
+}
@@ -15,7 +15,12 @@
 package geocollection
 
 import (
+	"bytes"
+	"math"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/geo/s2"
 	"github.com/stretchr/testify/assert"
@@ -102,8 +107,8 @@ func TestCollection_Set(t *testing.T) {
 			for _, expectedContains := range test.expectedCellIDContains {
 				expectedCellID := expectedContains.cellID
 				assert.Contains(t, cl.keys, expectedContains.item.key)
-				require.Contains(t, cl.cells[expectedCellID.Level()][expectedCellID.Pos()], expectedContains.item.key)
-				assert.Contains(t, cl.cells[expectedCellID.Level()], expectedCellID.Pos())
+				require.Contains(t, cl.cells[expectedCellID.Level()][expectedCellID], expectedContains.item.key)
+				assert.Contains(t, cl.cells[expectedCellID.Level()], expectedCellID)
 				require.Contains(t, cl.items, expectedContains.item.key)
 				assert.Equal(
 					t,
@@ -112,6 +117,8 @@ func TestCollection_Set(t *testing.T) {
 						contents:  expectedContains.item.contents,
 						latitude:  expectedContains.item.lat,
 						longitude: expectedContains.item.lon,
+						point:     NewPointFromLatLng(expectedContains.item.lat, expectedContains.item.lon),
+						cellID:    s2.CellIDFromLatLng(s2.LatLngFromDegrees(expectedContains.item.lat, expectedContains.item.lon)),
 					},
 				)
 			}
@@ -119,6 +126,255 @@ func TestCollection_Set(t *testing.T) {
 	}
 }
 
+func TestCollection_Set_sameLeafCell(t *testing.T) {
+	// these coordinates are a fraction of a millimeter apart and share a leaf cell
+	lat, lon := cell1.lat+1e-9, cell1.lon+1e-9
+	require.Equal(t,
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(cell1.lat, cell1.lon)),
+		s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lon)))
+
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	indices := cl.keys[0]
+	cl.Set(0, "1", lat, lon)
+	// the index entries were not rebuilt
+	require.Len(t, cl.keys[0], len(indices))
+	assert.Same(t, &indices[0], &cl.keys[0][0])
+	assert.Equal(t, "1", cl.items[0].contents)
+	assert.Equal(t, lat, cl.items[0].latitude)
+	assert.Equal(t, lon, cl.items[0].longitude)
+}
+
+func TestCollection_Set_roundedCoordinates(t *testing.T) {
+	// roundTrip formats a coordinate with fewer digits than it takes to represent it exactly and parses it back
+	roundTrip := func(degrees float64) float64 {
+		parsed, err := strconv.ParseFloat(strconv.FormatFloat(degrees, 'g', 15, 64), 64)
+		require.NoError(t, err)
+		return parsed
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{name: "Coordinates one ULP apart are unchanged", lat: math.Nextafter(cell1.lat, 90), lon: math.Nextafter(cell1.lon, 0)},
+		{name: "Coordinates rounded by serialization are unchanged", lat: roundTrip(cell1.lat), lon: roundTrip(cell1.lon)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.False(t, test.lat == cell1.lat && test.lon == cell1.lon)
+			cl := NewCollection()
+			cl.Set(0, "0", cell1.lat, cell1.lon)
+			indices := cl.keys[0]
+			cl.Set(0, "0", test.lat, test.lon)
+			assert.Same(t, &indices[0], &cl.keys[0][0])
+			assert.Equal(t, cell1.cellID, cl.items[0].cellID)
+		})
+	}
+}
+
+func TestCollection_SetBatch(t *testing.T) {
+	cl, items := randomCollection(100)
+	batch := make([]BatchItem, 0, len(items)+1)
+	for _, item := range items {
+		batch = append(batch, BatchItem{Key: item.Key, Contents: item.Contents, Latitude: item.Latitude, Longitude: item.Longitude})
+	}
+	// later items in a batch replace earlier ones with the same key
+	batch = append(batch, BatchItem{Key: 0, Contents: "moved", Latitude: cell2.lat, Longitude: cell2.lon})
+	cl.Set(0, "moved", cell2.lat, cell2.lon)
+
+	batched := NewCollection()
+	batched.SetBatch(batch)
+	assert.Equal(t, cl.items, batched.items)
+	assert.Equal(t, cl.keys, batched.keys)
+	assert.Equal(t, cl.cells, batched.cells)
+}
+
+func TestCollection_ReplaceAll(t *testing.T) {
+	cl := NewCollection()
+	cl.Set("old", "old", cell1.lat, cell1.lon)
+	_, items := randomCollection(100)
+	batch := make([]BatchItem, 0, len(items)+1)
+	for _, item := range items {
+		batch = append(batch, BatchItem{Key: item.Key, Contents: item.Contents, Latitude: item.Latitude, Longitude: item.Longitude})
+	}
+	batch = append(batch, BatchItem{Key: 0, Contents: "moved", Latitude: cell2.lat, Longitude: cell2.lon})
+
+	cl.ReplaceAll(batch)
+	batched := NewCollection()
+	batched.SetBatch(batch)
+	assert.Equal(t, batched.items, cl.items)
+	assert.Equal(t, batched.keys, cl.keys)
+	assert.False(t, cl.Has("old"))
+	assert.Equal(t, 100, cl.PeakLen())
+}
+
+func TestCollection_ReplaceAll_wal(t *testing.T) {
+	var wal bytes.Buffer
+	cl := NewCollection(WithWAL(&wal))
+	cl.Set("old", "old", cell1.lat, cell1.lon)
+	restored := NewCollection()
+	require.NoError(t, ReplayWAL(&wal, &restored))
+	wal.Reset()
+
+	cl.ReplaceAll([]BatchItem{
+		{Key: "a", Contents: "a", Latitude: cell1.lat, Longitude: cell1.lon},
+		{Key: "b", Contents: "b", Latitude: cell2.lat, Longitude: cell2.lon},
+		{Key: "a", Contents: "moved", Latitude: cell2.lat, Longitude: cell2.lon},
+	})
+	require.NoError(t, cl.WALError())
+	require.NoError(t, ReplayWAL(&wal, &restored))
+	assert.Equal(t, cl.items, restored.items)
+	assert.Equal(t, cl.keys, restored.keys)
+}
+
+func TestCollection_ReplaceAll_concurrent(t *testing.T) {
+	first := make([]BatchItem, 0, 100)
+	second := make([]BatchItem, 0, 200)
+	for i := 0; i < 200; i++ {
+		item := BatchItem{Key: i, Contents: i, Latitude: cell1.lat, Longitude: cell1.lon}
+		if i < 100 {
+			first = append(first, item)
+		}
+		second = append(second, item)
+	}
+	cl := NewCollection()
+	cl.ReplaceAll(first)
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			found := len(cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 100, params))
+			if found != len(first) && found != len(second) {
+				t.Errorf("search found %d items", found)
+				return
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		cl.ReplaceAll(second)
+		cl.ReplaceAll(first)
+	}
+	close(done)
+	wg.Wait()
+}
+
+// benchmarkLoad builds the items of a load of n random points
+func benchmarkLoad(n int) []BatchItem {
+	_, items := randomCollection(n)
+	batch := make([]BatchItem, 0, len(items))
+	for _, item := range items {
+		batch = append(batch, BatchItem{Key: item.Key, Contents: item.Contents, Latitude: item.Latitude, Longitude: item.Longitude})
+	}
+	return batch
+}
+
+func BenchmarkCollection_Set(b *testing.B) {
+	batch := benchmarkLoad(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cl := NewCollection()
+		for _, item := range batch {
+			cl.Set(item.Key, item.Contents, item.Latitude, item.Longitude)
+		}
+	}
+}
+
+func BenchmarkCollection_SetBatch(b *testing.B) {
+	batch := benchmarkLoad(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewCollection().SetBatch(batch)
+	}
+}
+
+func TestCollection_GetOrSet(t *testing.T) {
+	cl := NewCollection()
+	actual, loaded := cl.GetOrSet(0, "0", cell1.lat, cell1.lon)
+	assert.Equal(t, "0", actual)
+	assert.False(t, loaded)
+	actual, loaded = cl.GetOrSet(0, "1", cell2.lat, cell2.lon)
+	assert.Equal(t, "0", actual)
+	assert.True(t, loaded)
+	assert.Equal(t, cell1.lat, cl.items[0].latitude)
+	assert.Equal(t, cell1.lon, cl.items[0].longitude)
+}
+
+func TestCollection_GetOrSet_concurrent(t *testing.T) {
+	cl := NewCollection()
+	const goroutines = 50
+	results := make(chan interface{}, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual, _ := cl.GetOrSet(0, i, cell1.lat, cell1.lon)
+			results <- actual
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+	stored := cl.ItemByKey(0)
+	for actual := range results {
+		assert.Equal(t, stored, actual)
+	}
+}
+
+func TestCollection_SetToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		expectedErr    error
+		expectedLevels int
+	}{
+		{
+			name:           "Should set an item in a leaf cell",
+			token:          cell1.cellID.ToToken(),
+			expectedLevels: maxCellLevel + 1,
+		}, {
+			name:           "Should set an item in a larger cell",
+			token:          cell2.cellID.ToToken(),
+			expectedLevels: cell2.cellID.Level() + 1,
+		}, {
+			name:        "Should return an error for an invalid token",
+			token:       "zz",
+			expectedErr: ErrInvalidCoordinate,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := NewCollection()
+			err := cl.SetToken(0, "0", test.token)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				assert.NotContains(t, cl.items, 0)
+				return
+			}
+			require.NoError(t, err)
+			cellID := s2.CellIDFromToken(test.token)
+			assert.Len(t, cl.keys[0], test.expectedLevels)
+			assert.Contains(t, cl.cells[cellID.Level()][cellID], 0)
+			center := cellID.LatLng()
+			assert.Equal(t, collectionContents{
+				contents:  "0",
+				latitude:  center.Lat.Degrees(),
+				longitude: center.Lng.Degrees(),
+				point:     NewPointFromLatLng(center.Lat.Degrees(), center.Lng.Degrees()),
+				cellID:    cellID,
+			}, cl.items[0])
+		})
+	}
+}
+
 func TestCollection_Delete(t *testing.T) {
 	cell := cell1
 	item := testItem{key: 0, lat: cell.lat, lon: cell.lon}
@@ -144,9 +400,9 @@ func TestCollection_Delete(t *testing.T) {
 			cl.Delete(test.deleteKey)
 			assert.NotContains(t, cl.keys, test.deleteKey)
 			for level := maxCellLevel; level >= 0; level-- {
-				assert.NotContains(t, cl.cells[level][cell.cellID.Pos()], test.deleteKey)
+				assert.NotContains(t, cl.cells[level][cell.cellID.Parent(level)], test.deleteKey)
 				for _, remainingID := range test.expectedRemainingKeys {
-					assert.Contains(t, cl.cells[level][cell.cellID.Parent(level).Pos()], remainingID)
+					assert.Contains(t, cl.cells[level][cell.cellID.Parent(level)], remainingID)
 				}
 			}
 			for _, remainingID := range test.expectedRemainingKeys {
@@ -156,6 +412,361 @@ func TestCollection_Delete(t *testing.T) {
 	}
 }
 
+func TestCollection_Clone(t *testing.T) {
+	var wal bytes.Buffer
+	original := NewCollectionWithRadius(marsRadiusMeters, WithWAL(&wal), WithResultCacheTTL(time.Minute),
+		WithPruneStrategy(PruneImmediately()))
+	original.Set(0, "0", cell1.lat, cell1.lon)
+	original.Set(1, "1", cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	original.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	walLen := wal.Len()
+
+	clone := original.Clone()
+	assert.Equal(t, original.items, clone.items)
+	assert.Equal(t, original.keys, clone.keys)
+	assert.Equal(t, original.cells, clone.cells)
+	assert.Equal(t, marsRadiusMeters, clone.Radius())
+	assert.Equal(t, 2, clone.PeakLen())
+	assert.Empty(t, clone.cache.entries)
+	assert.Nil(t, clone.wal)
+
+	// changes to the clone do not affect the original
+	clone.Set(0, "moved", cell2.lat, cell2.lon)
+	clone.Delete(1)
+	clone.Set(2, "2", cell1.lat, cell1.lon)
+	assert.Equal(t, walLen, wal.Len())
+	found, _ := original.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Equal(t, []interface{}{"0"}, found)
+	assert.Equal(t, "1", original.ItemByKey(1))
+	assert.False(t, original.Has(2))
+	assert.Len(t, original.keys[0], maxCellLevel+1)
+
+	// and changes to the original do not affect the clone
+	original.Clear()
+	found, _ = clone.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Equal(t, []interface{}{"2"}, found)
+	found, _ = clone.ItemsWithinDistance(cell2.lat, cell2.lon, 1000, params)
+	assert.Equal(t, []interface{}{"moved"}, found)
+	assert.Equal(t, 2, clone.Count())
+}
+
+func TestCollection_CloneFunc(t *testing.T) {
+	type spot struct {
+		names []string
+	}
+	deepCopy := func(contents interface{}) interface{} {
+		s := contents.(*spot)
+		return &spot{names: append([]string(nil), s.names...)}
+	}
+	tests := []struct {
+		name          string
+		copyContents  func(interface{}) interface{}
+		expectedNames []string
+	}{
+		{"deep copies are isolated from the original", deepCopy, []string{"a"}},
+		{"nil copiers share contents like Clone", nil, []string{"modified"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := NewCollection()
+			original.Set(0, &spot{names: []string{"a"}}, cell1.lat, cell1.lon)
+			clone := original.CloneFunc(test.copyContents)
+			assert.Equal(t, original.keys, clone.keys)
+			assert.Equal(t, original.cells, clone.cells)
+
+			original.ItemByKey(0).(*spot).names[0] = "modified"
+			assert.Equal(t, test.expectedNames, clone.ItemByKey(0).(*spot).names)
+			found := clone.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000,
+				SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8})
+			assert.Equal(t, []interface{}{clone.ItemByKey(0)}, found)
+		})
+	}
+}
+
+func TestCollection_Merge(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "kept", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat, cell1.lon)
+	other := NewCollection()
+	other.Set(0, "ignored", cell2.lat, cell2.lon)
+	other.Set(2, "2", cell2.lat, cell2.lon)
+	require.NoError(t, other.SetToken(3, "3", cell2.cellID.Parent(12).ToToken()))
+
+	cl.Merge(other)
+	expected := NewCollection()
+	expected.Set(0, "kept", cell1.lat, cell1.lon)
+	expected.Set(1, "1", cell1.lat, cell1.lon)
+	expected.Set(2, "2", cell2.lat, cell2.lon)
+	require.NoError(t, expected.SetToken(3, "3", cell2.cellID.Parent(12).ToToken()))
+	assert.Equal(t, expected.items, cl.items)
+	assert.Equal(t, expected.keys, cl.keys)
+	params := SearchCoveringParameters{MinLevel: 12, MaxLevel: 12, LevelMod: 1, MaxCells: 8}
+	found, _ := cl.ItemsWithinDistance(cell2.lat, cell2.lon, 100, params)
+	assert.ElementsMatch(t, []interface{}{"2", "3"}, found)
+	// other is unchanged
+	assert.Equal(t, 3, other.Count())
+	assert.Equal(t, "ignored", other.ItemByKey(0))
+
+	cl.Merge(cl)
+	assert.Equal(t, 4, cl.Count())
+}
+
+func TestCollection_Merge_concurrent(t *testing.T) {
+	a, b := NewCollection(), NewCollection()
+	for i := 0; i < 100; i++ {
+		a.Set(i, i, cell1.lat, cell1.lon)
+		b.Set(i+100, i+100, cell2.lat, cell2.lon)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.Merge(b)
+		}()
+		go func() {
+			defer wg.Done()
+			b.Merge(a)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 200, a.Count())
+	assert.Equal(t, 200, b.Count())
+}
+
+func TestCollection_Remove(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	require.NoError(t, cl.Remove(0))
+	assert.NotContains(t, cl.items, 0)
+	assert.NotContains(t, cl.keys, 0)
+	assert.ErrorIs(t, cl.Remove(0), ErrKeyNotFound)
+}
+
+func TestCollection_Rekey(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	setup := func() Collection {
+		cl := NewCollection(WithClock(func() time.Time { return now }))
+		cl.Set(0, "0", cell1.lat, cell1.lon)
+		cl.Set(1, "1", cell2.lat, cell2.lon)
+		cl.SetWithTTL(3, "3", cell2.lat, cell2.lon, time.Nanosecond)
+		now = now.Add(time.Second)
+		return cl
+	}
+	tests := []struct {
+		name           string
+		oldKey, newKey interface{}
+		expected       bool
+		expectedItems  map[interface{}]interface{}
+	}{
+		{"items are moved to the new key", 0, "zero", true, map[interface{}]interface{}{"zero": "0", 1: "1"}},
+		{"missing keys are not moved", 4, "four", false, map[interface{}]interface{}{0: "0", 1: "1"}},
+		{"existing keys are not overwritten", 0, 1, false, map[interface{}]interface{}{0: "0", 1: "1"}},
+		{"keys are not moved to themselves", 0, 0, false, map[interface{}]interface{}{0: "0", 1: "1"}},
+		{"expired items are not moved", 3, "three", false, map[interface{}]interface{}{0: "0", 1: "1"}},
+		{"expired items are replaced", 0, 3, true, map[interface{}]interface{}{3: "0", 1: "1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := setup()
+			assert.Equal(t, test.expected, cl.Rekey(test.oldKey, test.newKey))
+			items := make(map[interface{}]interface{})
+			cl.ForEach(func(key, contents interface{}, _, _ float64) bool {
+				items[key] = contents
+				return true
+			})
+			assert.Equal(t, test.expectedItems, items)
+
+			// the index matches that of a collection the items were set in
+			expected := NewCollection()
+			for key := range test.expectedItems {
+				lat, lon, _ := cl.GetLocation(key)
+				expected.Set(key, test.expectedItems[key], lat, lon)
+			}
+			cl.PurgeExpired()
+			assert.Equal(t, expected.keys, cl.keys)
+			assert.Equal(t, expected.cells, cl.cells)
+		})
+	}
+}
+
+func TestCollection_Rekey_wal(t *testing.T) {
+	var wal bytes.Buffer
+	cl := NewCollection(WithWAL(&wal))
+	cl.Set("a", "a", cell1.lat, cell1.lon)
+	require.True(t, cl.Rekey("a", "b"))
+
+	replayed := NewCollection()
+	require.NoError(t, ReplayWAL(&wal, &replayed))
+	assert.False(t, replayed.Has("a"))
+	assert.Equal(t, "a", replayed.ItemByKey("b"))
+}
+
+func TestCollection_ReindexKey(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat, cell1.lon)
+	require.NoError(t, cl.SetToken(2, "2", cell1.cellID.Parent(12).ToToken()))
+	params := SearchCoveringParameters{MaxLevel: 12, MinLevel: 12, LevelMod: 1, MaxCells: 8}
+
+	// corrupt the index of the first item by dropping it from its level 12 cell and pointing its key at a cell in
+	// another city
+	delete(cl.cells[12][cell1.cellID.Parent(12)], 0)
+	cl.keys[0] = []itemIndex{{cellID: cell2.cellID.Parent(12), cellLevel: 12}}
+	cl.cells[12][cell2.cellID.Parent(12)] = map[interface{}]bool{0: true}
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 100, params)
+	assert.ElementsMatch(t, []interface{}{"1", "2"}, found)
+
+	assert.True(t, cl.ReindexKey(0))
+	found, _ = cl.ItemsWithinDistance(cell1.lat, cell1.lon, 100, params)
+	assert.ElementsMatch(t, []interface{}{"0", "1", "2"}, found)
+	found, _ = cl.ItemsWithinDistance(cell2.lat, cell2.lon, 100, params)
+	assert.Empty(t, found)
+	assert.Len(t, cl.keys[0], maxCellLevel+1)
+
+	// token items keep the level of their cell
+	assert.True(t, cl.ReindexKey(2))
+	assert.Equal(t, cell1.cellID.Parent(12), cl.items[2].cellID)
+	assert.Len(t, cl.keys[2], 13)
+
+	assert.False(t, cl.ReindexKey(3))
+}
+
+func TestCollection_UpdateLocation(t *testing.T) {
+	copies := 0
+	cl := NewCollection(WithContentsCopier(func(contents interface{}) interface{} {
+		copies++
+		return contents
+	}))
+	cl.SetWithHeading(0, "0", cell1.lat, cell1.lon, 90)
+	require.NoError(t, cl.SetToken(1, "1", cell1.cellID.Parent(12).ToToken()))
+	params := SearchCoveringParameters{MaxLevel: 12, MinLevel: 12, LevelMod: 1, MaxCells: 8}
+	copies = 0
+
+	assert.True(t, cl.UpdateLocation(0, cell2.lat, cell2.lon))
+	assert.Zero(t, copies)
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 100, params)
+	assert.Equal(t, []interface{}{"1"}, found)
+	found, _ = cl.ItemsWithinDistance(cell2.lat, cell2.lon, 100, params)
+	assert.Equal(t, []interface{}{"0"}, found)
+	assert.Equal(t, cell2.lat, cl.items[0].latitude)
+	assert.True(t, cl.items[0].hasHeading)
+	assert.Len(t, cl.keys[0], maxCellLevel+1)
+
+	// moving within the same leaf cell keeps the index as it is
+	assert.True(t, cl.UpdateLocation(0, cell2.lat+1e-9, cell2.lon))
+	assert.Equal(t, cell2.lat+1e-9, cl.items[0].latitude)
+	found, _ = cl.ItemsWithinDistance(cell2.lat, cell2.lon, 100, params)
+	assert.Equal(t, []interface{}{"0"}, found)
+
+	// token items are moved to the leaf cell of their new location
+	assert.True(t, cl.UpdateLocation(1, cell2.lat, cell2.lon))
+	assert.Len(t, cl.keys[1], maxCellLevel+1)
+	found, _ = cl.ItemsWithinDistance(cell1.lat, cell1.lon, 100, params)
+	assert.Empty(t, found)
+
+	assert.False(t, cl.UpdateLocation(2, cell1.lat, cell1.lon))
+	assert.Equal(t, 2, cl.Count())
+}
+
+func TestCollection_Clear(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell2.lat, cell2.lon)
+	cl.Clear()
+	assert.Zero(t, cl.Count())
+	assert.Empty(t, cl.keys)
+	assert.Zero(t, numCells(cl))
+	assert.Equal(t, 2, cl.PeakLen())
+
+	// the collection is usable after being cleared
+	cl.Set(2, "2", cell1.lat, cell1.lon)
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, SearchCoveringParameters{
+		MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8})
+	assert.Equal(t, []interface{}{"2"}, found)
+}
+
+func TestCollection_DeleteWithinDistance(t *testing.T) {
+	// a coarse covering that reaches well past the search radius
+	coarse := SearchCoveringParameters{MaxLevel: 8, MinLevel: 8, LevelMod: 1, MaxCells: 1}
+	tests := []struct {
+		name            string
+		distanceMeters  float64
+		expectedRemoved int
+		expectedLeft    []interface{}
+	}{
+		{"items in covering cells past the radius are kept", 500, 2, []interface{}{2, 3}},
+		{"every item within the radius is removed", 1500, 3, []interface{}{3}},
+		{"invalid distances remove nothing", -1, 0, []interface{}{0, 1, 2, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := NewCollection()
+			cl.Set(0, 0, cell1.lat, cell1.lon)
+			cl.Set(1, 1, cell1.lat, cell1.lon+0.001)
+			// about 1km north, in the same level 8 cell
+			cl.Set(2, 2, cell1.lat+0.009, cell1.lon)
+			cl.Set(3, 3, cell2.lat, cell2.lon)
+			approximate, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 500, coarse)
+			require.Len(t, approximate, 3)
+
+			assert.Equal(t, test.expectedRemoved, cl.DeleteWithinDistance(cell1.lat, cell1.lon, test.distanceMeters, coarse))
+			assert.Equal(t, len(test.expectedLeft), cl.Count())
+			for _, key := range test.expectedLeft {
+				assert.True(t, cl.Has(key), "key %v", key)
+			}
+			assert.Len(t, cl.keys, len(test.expectedLeft))
+		})
+	}
+}
+
+func TestCollection_DeleteOlderThan(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := start
+	clock := func() time.Time { return now }
+	tests := []struct {
+		name            string
+		opts            []Option
+		expectedRemoved int
+		expectedKeys    []int
+		expectedNearby  int
+	}{
+		{
+			name:            "Items set before the cutoff are removed",
+			opts:            []Option{WithUpdateTimestamps(), WithClock(clock)},
+			expectedRemoved: 2,
+			expectedKeys:    []int{2},
+			expectedNearby:  1,
+		}, {
+			name:            "Nothing is removed without update timestamps",
+			opts:            []Option{WithClock(clock)},
+			expectedRemoved: 0,
+			expectedKeys:    []int{0, 1, 2},
+			expectedNearby:  2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			now = start
+			cl := NewCollection(test.opts...)
+			cl.Set(0, "0", cell1.lat, cell1.lon)
+			cl.Set(1, "1", cell2.lat, cell2.lon)
+			now = start.Add(time.Hour)
+			cl.Set(2, "2", cell2.lat, cell2.lon)
+
+			assert.Equal(t, test.expectedRemoved, cl.DeleteOlderThan(start.Add(time.Minute)))
+			assert.Len(t, cl.items, len(test.expectedKeys))
+			for _, key := range test.expectedKeys {
+				assert.Contains(t, cl.items, key)
+			}
+			results, _ := cl.ItemsWithinDistance(cell2.lat, cell2.lon, 1000, SearchCoveringParameters{
+				MaxLevel: 5, MinLevel: 5, LevelMod: 1, MaxCells: 5})
+			assert.Len(t, results, test.expectedNearby)
+		})
+	}
+}
+
 func TestCollection_ItemsWithinDistance(t *testing.T) {
 	item1 := testItem{key: 0, contents: "1", lat: cell1.lat, lon: cell1.lon}
 	item2 := testItem{key: 1, contents: "2", lat: cell2.lat, lon: cell2.lon}
@@ -203,13 +814,591 @@ func TestCollection_ItemsWithinDistance(t *testing.T) {
 			cl := NewCollection()
 			cl.Set(item1.key, item1.contents, item1.lat, item1.lon)
 			cl.Set(item2.key, item2.contents, item2.lat, item2.lon)
-			results, _ := cl.ItemsWithinDistance(
-				test.searchLat, test.searchLon, test.distanceMeters, SearchCoveringParameters{
-					MaxLevel: 5, MinLevel: 5, LevelMod: 1, MaxCells: 5, UseFastCovering: test.useFastAlgorithm})
+			params := SearchCoveringParameters{
+				MaxLevel: 5, MinLevel: 5, LevelMod: 1, MaxCells: 5, UseFastCovering: test.useFastAlgorithm}
+			results, _ := cl.ItemsWithinDistance(test.searchLat, test.searchLon, test.distanceMeters, params)
 			assert.Len(t, results, len(test.expectedContents))
 			for _, content := range test.expectedContents {
 				assert.Contains(t, results, content)
 			}
+			assert.ElementsMatch(
+				t, results, cl.ItemsWithinDistanceOnly(test.searchLat, test.searchLon, test.distanceMeters, params))
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistance_sortByDistance(t *testing.T) {
+	cl, all := randomCollection(500)
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+	unsorted := cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 20000, params)
+	// randomCollection sorts the items by their distance from cell1 and then by key, and uses keys as contents
+	inResults := make(map[interface{}]bool, len(unsorted))
+	for _, contents := range unsorted {
+		inResults[contents] = true
+	}
+	expected := make([]interface{}, 0, len(unsorted))
+	expectedKeys := make([]KeyDistance, 0, len(unsorted))
+	for _, item := range all {
+		if inResults[item.Contents] {
+			expected = append(expected, item.Contents)
+			expectedKeys = append(expectedKeys, KeyDistance{Key: item.Key, DistanceMeters: item.DistanceMeters})
+		}
+	}
+	require.Greater(t, len(expected), 100)
+
+	params.SortByDistance = true
+	cached := NewCollection(WithResultCacheTTL(time.Minute))
+	for _, item := range all {
+		cached.Set(item.Key, item.Contents, item.Latitude, item.Longitude)
+	}
+	tests := []struct {
+		name   string
+		search func() []interface{}
+	}{
+		{"ItemsWithinDistance", func() []interface{} {
+			found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 20000, params)
+			return found
+		}},
+		{"ItemsWithinDistanceOnly", func() []interface{} {
+			return cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 20000, params)
+		}},
+		{"cached searches", func() []interface{} {
+			cached.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 20000, params)
+			found, _ := cached.ItemsWithinDistance(cell1.lat, cell1.lon, 20000, params)
+			return found
+		}},
+		{"frozen snapshots", func() []interface{} {
+			found, _ := cl.FrozenSnapshot().ItemsWithinDistance(cell1.lat, cell1.lon, 20000, params)
+			return found
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, expected, test.search())
+		})
+	}
+	assert.Equal(t, expectedKeys, cl.KeysWithDistancesWithinDistance(cell1.lat, cell1.lon, 20000, params))
+}
+
+func TestCollection_ItemsWithinDistanceFunc(t *testing.T) {
+	cl := NewCollection()
+	for i := 0; i < 10; i++ {
+		cl.Set(i, i, cell1.lat, cell1.lon)
+	}
+	cl.Set(10, 10, cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+
+	found := make([]interface{}, 0)
+	cl.ItemsWithinDistanceFunc(cell1.lat, cell1.lon, 1000, params, func(key, contents interface{}) bool {
+		assert.Equal(t, key, contents)
+		found = append(found, contents)
+		return true
+	})
+	assert.ElementsMatch(t, cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000, params), found)
+
+	calls := 0
+	cl.ItemsWithinDistanceFunc(cell1.lat, cell1.lon, 1000, params, func(_, _ interface{}) bool {
+		calls++
+		return calls < 3
+	})
+	assert.Equal(t, 3, calls)
+
+	// duplicate contents are only passed once with DistinctContents
+	cl.Set(11, 0, cell1.lat, cell1.lon)
+	distinct := params
+	distinct.DistinctContents = true
+	found = make([]interface{}, 0)
+	cl.ItemsWithinDistanceFunc(cell1.lat, cell1.lon, 1000, distinct, func(_, contents interface{}) bool {
+		found = append(found, contents)
+		return true
+	})
+	assert.ElementsMatch(t, []interface{}{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, found)
+
+	cl.ItemsWithinDistanceFunc(cell1.lat, cell1.lon, -1, params, func(_, _ interface{}) bool {
+		t.Error("fn called for an invalid search")
+		return true
+	})
+}
+
+func TestCollection_CountWithinDistance(t *testing.T) {
+	cl := NewCollection()
+	for i := 0; i < 10; i++ {
+		cl.Set(i, i, cell1.lat, cell1.lon)
+	}
+	// about 1050m away, just past the search radius but within the covering
+	cl.Set(10, 10, cell1.lat+0.0095, cell1.lon)
+	cl.Set(11, 11, cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+
+	assert.Equal(t, len(cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000, params)),
+		cl.CountWithinDistance(cell1.lat, cell1.lon, 1000, params))
+	assert.Equal(t, 11, cl.CountWithinDistance(cell1.lat, cell1.lon, 1000, params))
+	assert.Equal(t, 10, cl.CountWithinDistancePrecise(cell1.lat, cell1.lon, 1000, params))
+	assert.Zero(t, cl.CountWithinDistance(cell1.lat, cell1.lon, -1, params))
+	assert.Zero(t, cl.CountWithinDistancePrecise(math.NaN(), cell1.lon, 1000, params))
+}
+
+func TestCollection_ItemsWithinDistanceE(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 0, LevelMod: 1, MaxCells: 8}
+	tests := []struct {
+		name               string
+		lat, lon, distance float64
+		expectedErr        error
+		expectedLen        int
+	}{
+		{"valid searches return results", cell1.lat, cell1.lon, 1000, nil, 1},
+		{"searches at the limits of the ranges are valid", -90, 180, 0, nil, 0},
+		{"latitudes past the poles are invalid", 200, cell1.lon, 1000, ErrInvalidCoordinate, 0},
+		{"longitudes past the antimeridian are invalid", cell1.lat, 999, 1000, ErrInvalidCoordinate, 0},
+		{"NaN coordinates are invalid", math.NaN(), cell1.lon, 1000, ErrInvalidCoordinate, 0},
+		{"negative distances are invalid", cell1.lat, cell1.lon, -1, ErrInvalidDistance, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, covering, err := cl.ItemsWithinDistanceE(test.lat, test.lon, test.distance, params)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				assert.Empty(t, covering)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, covering)
+			}
+			assert.Len(t, results, test.expectedLen)
+			legacy, _ := cl.ItemsWithinDistance(test.lat, test.lon, test.distance, params)
+			assert.Equal(t, results, legacy)
+		})
+	}
+}
+
+func TestCollection_searches_invalidInput(t *testing.T) {
+	cl := NewCollection()
+	// latitudes past the poles wrap around onto the other side of the sphere, so (160, 180) is the point (20, 0)
+	cl.Set(0, "0", 20, 0)
+	cl.Set(1, "1", cell1.lat, cell1.lon)
+	frozen := cl.FrozenSnapshot()
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	searches := map[string]func(lat, lon, distance float64) int{
+		"ItemsWithinDistance": func(lat, lon, distance float64) int {
+			found, _ := cl.ItemsWithinDistance(lat, lon, distance, params)
+			return len(found)
+		},
+		"ItemsWithinDistanceOnly": func(lat, lon, distance float64) int {
+			return len(cl.ItemsWithinDistanceOnly(lat, lon, distance, params))
+		},
+		"ItemsWithinDistanceWithMeta": func(lat, lon, distance float64) int {
+			found, _ := cl.ItemsWithinDistanceWithMeta(lat, lon, distance, params)
+			return len(found)
+		},
+		"KeysWithDistancesWithinDistance": func(lat, lon, distance float64) int {
+			return len(cl.KeysWithDistancesWithinDistance(lat, lon, distance, params))
+		},
+		"ItemsWithinDistancePaged": func(lat, lon, distance float64) int {
+			return len(cl.ItemsWithinDistancePaged(lat, lon, distance, params, 10, 0))
+		},
+		"ItemsWithinDistanceFiltered": func(lat, lon, distance float64) int {
+			found, _ := cl.ItemsWithinDistanceFiltered(lat, lon, distance, params)
+			return len(found)
+		},
+		"ItemsWithinDistanceSortedBy": func(lat, lon, distance float64) int {
+			return len(cl.ItemsWithinDistanceSortedBy(lat, lon, distance,
+				func(a, b interface{}) bool { return a.(string) < b.(string) }, params))
+		},
+		"ItemsWithinDistanceOfAny": func(lat, lon, distance float64) int {
+			return len(cl.ItemsWithinDistanceOfAny([][2]float64{{cell1.lat, cell1.lon}, {lat, lon}}, distance, params))
+		},
+		"WithinDistanceBanded": func(lat, lon, distance float64) int {
+			n := 0
+			cl.WithinDistanceBanded(lat, lon, distance, 100, func(_ int, items []LocatedItem) bool {
+				n += len(items)
+				return true
+			}, params)
+			return n
+		},
+		"ItemsWithinDistanceStats": func(lat, lon, distance float64) int {
+			found, _ := cl.ItemsWithinDistanceStats(lat, lon, distance, params)
+			return len(found)
+		},
+		"FrozenCollection.ItemsWithinDistance": func(lat, lon, distance float64) int {
+			found, _ := frozen.ItemsWithinDistance(lat, lon, distance, params)
+			return len(found)
+		},
+		"FrozenCollection.ItemsWithinDistanceOnly": func(lat, lon, distance float64) int {
+			return len(frozen.ItemsWithinDistanceOnly(lat, lon, distance, params))
+		},
+	}
+	tests := []struct {
+		name               string
+		lat, lon, distance float64
+	}{
+		{"latitudes past the poles", 160, 180, 1000},
+		{"longitudes past the antimeridian", cell1.lat, cell1.lon + 360, 1000},
+		{"NaN coordinates", math.NaN(), cell1.lon, 1000},
+		{"negative distances", cell1.lat, cell1.lon, -1},
+	}
+	for name, search := range searches {
+		// every search finds the items at valid coordinates
+		require.Positive(t, search(cell1.lat, cell1.lon, 1000), name)
+		for _, test := range tests {
+			t.Run(name+" "+test.name, func(t *testing.T) {
+				assert.Zero(t, search(test.lat, test.lon, test.distance))
+			})
+		}
+	}
+}
+
+func TestCollection_ItemsWithinDistanceE_params(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	// the coverer treats a LevelMod of zero as one, so only ItemsWithinDistanceE rejects the parameters
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, MaxCells: 8}
+	results, covering, err := cl.ItemsWithinDistanceE(cell1.lat, cell1.lon, 1000, params)
+	assert.ErrorIs(t, err, ErrInvalidCoveringParams)
+	assert.Empty(t, results)
+	assert.Empty(t, covering)
+	legacy, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Equal(t, []interface{}{"0"}, legacy)
+}
+
+func TestCollection_ItemsWithinDistanceWithMeta(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat+0.009, cell1.lon)
+	cl.Set(2, "2", cell2.lat, cell2.lon)
+	// a coarse covering that reaches well past the search radius
+	params := SearchCoveringParameters{MaxLevel: 8, MinLevel: 8, LevelMod: 1, MaxCells: 1}
+
+	approximate, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 500, params)
+	require.Len(t, approximate, 2)
+	found, bounds := cl.ItemsWithinDistanceWithMeta(cell1.lat, cell1.lon, 500, params)
+	assert.Equal(t, []ItemResult{{Key: 0, Contents: "0", Latitude: cell1.lat, Longitude: cell1.lon}}, found)
+	assert.NotEmpty(t, bounds)
+
+	found, _ = cl.ItemsWithinDistanceWithMeta(cell1.lat, cell1.lon, 1500, params)
+	require.Len(t, found, 2)
+	for _, item := range found {
+		assert.InDelta(t, EarthDistanceMeters(
+			NewPointFromLatLng(cell1.lat, cell1.lon), NewPointFromLatLng(item.Latitude, item.Longitude)), item.DistanceMeters, 1e-9)
+	}
+}
+
+func TestCollection_ItemsWithinDistance_radiusMargin(t *testing.T) {
+	center := NewPointFromLatLng(cell1.lat, cell1.lon)
+	edge := NewPointFromLatLng(cell1.lat+0.01, cell1.lon)
+	distance := EarthDistanceMeters(center, edge)
+	cl := NewCollection()
+	cl.Set(0, "edge", cell1.lat+0.01, cell1.lon)
+	cl.Set(1, "past the edge", cell1.lat+0.0105, cell1.lon)
+	tests := []struct {
+		name           string
+		marginMeters   float64
+		marginFraction float64
+		expectedMeters float64
+	}{
+		{"no margin by default", 0, 0, distance},
+		{"absolute margin", 100, 0, distance + 100},
+		{"fractional margin", 0, 0.1, distance * 1.1},
+		{"both margins", 100, 0.1, distance*1.1 + 100},
+		{"negative margins are ignored", -100, -0.1, distance},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			params := SearchCoveringParameters{
+				MinLevel: 0, MaxLevel: 30, LevelMod: 1, MaxCells: 8,
+				RadiusMarginMeters: test.marginMeters, RadiusMarginFraction: test.marginFraction,
+			}
+			searchCap := params.searchCap(center, distance, EarthRadiusMeters)
+			assert.InDelta(t, test.expectedMeters, searchCap.Radius().Radians()*EarthRadiusMeters, 1e-6)
+
+			results, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, distance, params)
+			assert.Contains(t, results, "edge")
+			// exact filtering trims the extra items the margin brings in
+			found, _ := cl.ItemsWithinDistanceWithMeta(cell1.lat, cell1.lon, distance, params)
+			require.Len(t, found, 1)
+			assert.Equal(t, "edge", found[0].Contents)
+		})
+	}
+}
+
+func TestCollection_KeysWithDistancesWithinDistance(t *testing.T) {
+	// count every time contents are copied to show that contents are never read
+	copies := 0
+	cl := NewCollection(WithContentsCopier(func(contents interface{}) interface{} {
+		copies++
+		return contents
+	}))
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat+0.009, cell1.lon)
+	cl.Set(2, "2", cell2.lat, cell2.lon)
+	copies = 0
+
+	found := cl.KeysWithDistancesWithinDistance(cell1.lat, cell1.lon, 2000, SearchCoveringParameters{
+		MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8})
+	require.Len(t, found, 2)
+	distances := make(map[interface{}]float64)
+	for _, item := range found {
+		distances[item.Key] = item.DistanceMeters
+	}
+	assert.Zero(t, distances[0])
+	assert.InDelta(t, EarthDistanceMeters(
+		NewPointFromLatLng(cell1.lat, cell1.lon), NewPointFromLatLng(cell1.lat+0.009, cell1.lon)), distances[1], 1e-9)
+	assert.InDelta(t, 1000, distances[1], 10)
+	assert.Zero(t, copies)
+}
+
+func TestCollection_ItemsWithinDistancePaged(t *testing.T) {
+	cl := NewCollection()
+	// items due north of chicago at increasing distances, plus one just outside the search in a covering cell
+	for i := 0; i < 5; i++ {
+		cl.Set(i, i, cell1.lat+float64(i)*0.001, cell1.lon)
+	}
+	cl.Set(5, 5, cell1.lat+0.006, cell1.lon)
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 10, LevelMod: 1, MaxCells: 1}
+	require.Contains(t, cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 500, params), 5)
+	tests := []struct {
+		name            string
+		pageSize, start int
+		expected        []interface{}
+	}{
+		{"first page", 2, 0, []interface{}{0, 1}},
+		{"second page", 2, 2, []interface{}{2, 3}},
+		{"last partial page", 2, 4, []interface{}{4}},
+		{"page past the end", 2, 6, []interface{}{}},
+		{"page covering everything", 10, 0, []interface{}{0, 1, 2, 3, 4}},
+		{"empty page", 0, 0, []interface{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(
+				t, test.expected, cl.ItemsWithinDistancePaged(cell1.lat, cell1.lon, 500, params, test.pageSize, test.start))
+		})
+	}
+}
+
+func TestCollection_ItemsInDistanceRange(t *testing.T) {
+	cl := NewCollection()
+	// items due north of chicago, roughly 0, 1.1, 2.2 and 3.3km away
+	for i := 0; i < 4; i++ {
+		cl.Set(i, i, cell1.lat+float64(i)*0.01, cell1.lon)
+	}
+	oneKm := EarthDistanceMeters(NewPointFromLatLng(cell1.lat, cell1.lon), NewPointFromLatLng(cell1.lat+0.01, cell1.lon))
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 16, LevelMod: 1, MaxCells: 8, SortByDistance: true}
+	tests := []struct {
+		name                 string
+		minMeters, maxMeters float64
+		expected             []interface{}
+	}{
+		{"everything", 0, 5000, []interface{}{0, 1, 2, 3}},
+		{"excluding the center", 500, 5000, []interface{}{1, 2, 3}},
+		{"ring around the center", 1000, 2500, []interface{}{1, 2}},
+		{"bounds are inclusive", oneKm, oneKm, []interface{}{1}},
+		{"empty ring", 1200, 2100, []interface{}{}},
+		{"minimum greater than maximum", 3000, 1000, []interface{}{}},
+		{"negative maximum", -1, -1, []interface{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(
+				t, test.expected, cl.ItemsInDistanceRange(cell1.lat, cell1.lon, test.minMeters, test.maxMeters, params))
+		})
+	}
+}
+
+func TestCollection_ItemsAtPoint(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat, cell1.lon)
+	// a rounding error away from the point
+	cl.Set(2, "2", cell1.lat+1e-12, cell1.lon)
+	// a few millimeters away from the point
+	cl.Set(3, "3", cell1.lat+5e-8, cell1.lon)
+	cl.Set(4, "4", cell2.lat, cell2.lon)
+
+	found := cl.ItemsAtPoint(cell1.lat, cell1.lon)
+	keys := make([]interface{}, 0, len(found))
+	for _, item := range found {
+		keys = append(keys, item.Key)
+		assert.InDelta(t, 0, item.DistanceMeters, 1e-6)
+	}
+	assert.Equal(t, []interface{}{0, 1, 2}, keys)
+	assert.Equal(t, "0", found[0].Contents)
+	assert.Empty(t, cl.ItemsAtPoint(cell1.lat+1e-6, cell1.lon))
+}
+
+func TestCollection_ItemsAtLocation(t *testing.T) {
+	cl := NewCollection()
+	leaf := s2.CellFromCellID(cell1.cellID)
+	center := cell1.cellID.LatLng()
+	// a point inside the same leaf cell, a short way from its center towards its first vertex
+	nearby := s2.LatLngFromPoint(s2.Interpolate(0.5, leaf.Center(), leaf.Vertex(0)))
+	cl.Set(1, "1", center.Lat.Degrees(), center.Lng.Degrees())
+	cl.Set(0, "0", center.Lat.Degrees(), center.Lng.Degrees())
+	cl.Set(2, "2", nearby.Lat.Degrees(), nearby.Lng.Degrees())
+	cl.Set(3, "3", cell2.lat, cell2.lon)
+	require.NoError(t, cl.SetToken(4, "4", cell1.cellID.Parent(12).ToToken()))
+	require.NoError(t, cl.SetToken(5, "5", cell1.cellID.ToToken()))
+
+	tests := []struct {
+		name     string
+		lat, lon float64
+		expected []interface{}
+	}{
+		{"items in the same leaf cell", center.Lat.Degrees(), center.Lng.Degrees(), []interface{}{"0", "1", "2", "5"}},
+		{"single item", cell2.lat, cell2.lon, []interface{}{"3"}},
+		{"no items", 0, 0, []interface{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.ItemsAtLocation(test.lat, test.lon))
+		})
+	}
+}
+
+func TestCollection_ItemsInCellToken(t *testing.T) {
+	tests := []struct {
+		name     string
+		cl       Collection
+		token    string
+		expected []interface{}
+	}{
+		{"items in a leaf cell", NewCollection(), cell1.cellID.ToToken(), []interface{}{"0", "1"}},
+		{"items in a parent cell", NewCollection(), cell1.cellID.Parent(12).ToToken(), []interface{}{"0", "1", "2", "4"}},
+		{"items stored by a coarser token are not in its children", NewCollection(),
+			cell1.cellID.Parent(14).ToToken(), []interface{}{"0", "1", "2"}},
+		{"cells finer than the indexed levels", NewCollectionWithLevels(8, 12), cell1.cellID.Parent(14).ToToken(),
+			[]interface{}{"0", "1", "2"}},
+		{"cells coarser than the indexed levels", NewCollectionWithLevels(12, 16), cell1.cellID.Parent(10).ToToken(),
+			[]interface{}{"0", "1", "2", "4"}},
+		{"cells without items", NewCollection(), cell2.cellID.Parent(20).Next().ToToken(), []interface{}{}},
+		{"malformed tokens", NewCollection(), "not a token", []interface{}{}},
+		{"empty tokens", NewCollection(), "", []interface{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			center := cell1.cellID.LatLng()
+			test.cl.Set(1, "1", center.Lat.Degrees(), center.Lng.Degrees())
+			test.cl.Set(0, "0", center.Lat.Degrees(), center.Lng.Degrees())
+			// a neighbor of cell1 within the same level 14 cell
+			neighbor := cell1.cellID.Next().LatLng()
+			test.cl.Set(2, "2", neighbor.Lat.Degrees(), neighbor.Lng.Degrees())
+			test.cl.Set(3, "3", cell2.lat, cell2.lon)
+			require.NoError(t, test.cl.SetToken(4, "4", cell1.cellID.Parent(12).ToToken()))
+			assert.Equal(t, test.expected, test.cl.ItemsInCellToken(test.token))
+		})
+	}
+}
+
+func TestCollection_LevelIsQueryable(t *testing.T) {
+	cl := NewCollection()
+	require.NoError(t, cl.SetToken(0, "0", cell2.cellID.ToToken()))
+	tests := []struct {
+		name           string
+		level          int
+		expectedOK     bool
+		expectedReason string
+	}{
+		{name: "Indexed level is queryable", level: 5, expectedOK: true},
+		{name: "Level of the item's cell is queryable", level: cell2.cellID.Level(), expectedOK: true},
+		{
+			name:           "Level below the item's cell has no items",
+			level:          cell2.cellID.Level() + 1,
+			expectedReason: "no items are indexed at level 21",
+		}, {
+			name:           "Negative level is out of range",
+			level:          -1,
+			expectedReason: "level -1 is outside the indexed levels 0 to 30",
+		}, {
+			name:           "Level past the leaf level is out of range",
+			level:          31,
+			expectedReason: "level 31 is outside the indexed levels 0 to 30",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, reason := cl.LevelIsQueryable(test.level)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedReason, reason)
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistance_faces(t *testing.T) {
+	// cells in the same position of different cube faces must not share an index entry
+	cl := NewCollection()
+	cl.Set(0, "chicago", cell1.lat, cell1.lon)
+	cl.Set(1, "sydney", -33.86, 151.2)
+	results, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, SearchCoveringParameters{
+		MaxLevel: 0, MinLevel: 0, LevelMod: 1, MaxCells: 1})
+	assert.Equal(t, []interface{}{"chicago"}, results)
+}
+
+func TestCollection_ItemsWithinCapCoverer(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell2.lat, cell2.lon)
+	center := NewPointFromLatLng(cell1.lat, cell1.lon)
+	coverer := &s2.RegionCoverer{MinLevel: 10, MaxLevel: 10, LevelMod: 1, MaxCells: 1}
+	expectedCells := coverer.Covering(SearchCoveringParameters{}.searchCap(center, 20000, cl.radiusMeters))
+	require.Greater(t, len(expectedCells), 1)
+
+	results, covering := cl.ItemsWithinCapCoverer(center, 20000, coverer)
+	assert.Equal(t, []interface{}{"0"}, results)
+	assert.Len(t, covering, len(expectedCells))
+
+	results, covering = cl.ItemsInRegionCoverer(s2.CellFromCellID(cell2.cellID), coverer)
+	assert.Equal(t, []interface{}{"1"}, results)
+	assert.Len(t, covering, 1)
+}
+
+func TestCollection_ItemsInCellUnion(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell2.lat, cell2.lon)
+	coverer := &s2.RegionCoverer{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+	cellUnion := coverer.Covering(
+		SearchCoveringParameters{}.searchCap(NewPointFromLatLng(cell1.lat, cell1.lon), 1000, cl.radiusMeters))
+	tests := []struct {
+		name      string
+		cellUnion s2.CellUnion
+		expected  []interface{}
+	}{
+		{"covering of a search area", cellUnion, []interface{}{"0"}},
+		{"cells at different levels", s2.CellUnion{cell1.cellID.Parent(4), cell2.cellID}, []interface{}{"0", "1"}},
+		{"leaf cell", s2.CellUnion{cell2.cellID}, []interface{}{"1"}},
+		{"cell without items", s2.CellUnion{cell1.cellID.Parent(10).Next()}, []interface{}{}},
+		{"empty union", s2.CellUnion{}, []interface{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.ElementsMatch(t, test.expected, cl.ItemsInCellUnion(test.cellUnion))
+		})
+	}
+}
+
+func TestCollection_ItemsInBoundingBox(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "chicago", cell1.lat, cell1.lon)
+	cl.Set(1, "manhattan", cell2.lat, cell2.lon)
+	cl.Set(2, "fiji", -17.7, 178.1)
+	cl.Set(3, "samoa", -13.8, -171.8)
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 16, LevelMod: 1, MaxCells: 32}
+	tests := []struct {
+		name                           string
+		minLat, minLon, maxLat, maxLon float64
+		expected                       []interface{}
+	}{
+		{"rectangle around chicago", 41, -88.5, 42.5, -87, []interface{}{"chicago"}},
+		{"rectangle around the eastern united states", 35, -90, 45, -70, []interface{}{"chicago", "manhattan"}},
+		{"rectangle crossing the antimeridian", -20, 175, -10, -170, []interface{}{"fiji", "samoa"}},
+		{"rectangle spanning the other way around", 10, -170, 50, 175, []interface{}{"chicago", "manhattan"}},
+		{"empty area", 10, 10, 11, 11, []interface{}{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, covering := cl.ItemsInBoundingBox(test.minLat, test.minLon, test.maxLat, test.maxLon, params)
+			assert.ElementsMatch(t, test.expected, results)
+			assert.NotEmpty(t, covering)
 		})
 	}
 }
@@ -232,6 +1421,80 @@ func TestCollection_ItemByKey(t *testing.T) {
 	}
 }
 
+func TestCollection_ItemByKeyOK(t *testing.T) {
+	c := NewCollection()
+	c.Set(1, "1", cell1.lat, cell1.lon)
+	c.Set(2, nil, cell1.lat, cell1.lon)
+	tests := []struct {
+		name             string
+		key              interface{}
+		expectedContents interface{}
+		expectedOK       bool
+	}{
+		{name: "Stored keys are found", key: 1, expectedContents: "1", expectedOK: true},
+		{name: "Keys stored with nil contents are found", key: 2, expectedOK: true},
+		{name: "Keys that are not stored are not found", key: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contents, ok := c.ItemByKeyOK(test.key)
+			assert.Equal(t, test.expectedContents, contents)
+			assert.Equal(t, test.expectedOK, ok)
+		})
+	}
+}
+
+func TestCollection_GetLocation(t *testing.T) {
+	c := NewCollection()
+	c.Set(1, "1", cell1.lat, cell1.lon)
+	require.NoError(t, c.SetToken(2, "2", cell2.cellID.ToToken()))
+	center := cell2.cellID.LatLng()
+	tests := []struct {
+		name        string
+		key         interface{}
+		expectedLat float64
+		expectedLon float64
+		expectedOK  bool
+	}{
+		{name: "Stored coordinates are returned", key: 1, expectedLat: cell1.lat, expectedLon: cell1.lon, expectedOK: true},
+		{
+			name: "Token items return the center of their cell", key: 2,
+			expectedLat: center.Lat.Degrees(), expectedLon: center.Lng.Degrees(), expectedOK: true,
+		},
+		{name: "Keys that are not stored are not found", key: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lat, lon, ok := c.GetLocation(test.key)
+			assert.Equal(t, test.expectedOK, ok)
+			assert.Equal(t, test.expectedLat, lat)
+			assert.Equal(t, test.expectedLon, lon)
+		})
+	}
+}
+
+func TestCollection_Has(t *testing.T) {
+	c := NewCollection()
+	c.Set(1, "1", cell1.lat, cell1.lon)
+	c.Set(2, nil, cell1.lat, cell1.lon)
+	tests := []struct {
+		name     string
+		key      interface{}
+		expected bool
+	}{
+		{name: "Stored keys exist", key: 1, expected: true},
+		{name: "Keys stored with nil contents exist", key: 2, expected: true},
+		{name: "Keys that were never stored do not exist", key: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, c.Has(test.key))
+		})
+	}
+	c.Delete(1)
+	assert.False(t, c.Has(1))
+}
+
 func TestCollection_GetItems(t *testing.T) {
 	c := NewCollection()
 	// using the same contents value because map to slice isn't ordered always.
@@ -287,6 +1550,179 @@ func TestCollection_GetItems(t *testing.T) {
 	}
 }
 
+func TestCollection_GetItems_pages(t *testing.T) {
+	c := NewCollection()
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, cell1.lat, cell1.lon)
+	}
+	c.Set("a", "a", cell1.lat, cell1.lon)
+	found := make([]interface{}, 0)
+	for start := 0; start < 101; start += 7 {
+		page := c.GetItems(7, start)
+		assert.Equal(t, page, c.GetItems(7, start))
+		found = append(found, page...)
+	}
+	expected := make([]interface{}, 0, 101)
+	for i := 0; i < 100; i++ {
+		expected = append(expected, i)
+	}
+	assert.Equal(t, append(expected, "a"), found)
+	assert.Equal(t, []KeyedItem{{Key: 7, Contents: 7}, {Key: 8, Contents: 8}}, c.GetItemsWithKeys(2, 7))
+}
+
+func TestCollection_GetItemsWithKeys(t *testing.T) {
+	c := NewCollection()
+	c.Set(0, "0", cell1.lat, cell1.lon)
+	c.Set(1, "1", cell2.lat, cell2.lon)
+	tests := []struct {
+		name        string
+		startIndex  int
+		pageSize    int
+		expectedLen int
+	}{
+		{name: "All items are retrieved from collection", startIndex: 0, pageSize: 10, expectedLen: 2},
+		{name: "All items are retrieved from startIndex", startIndex: 1, pageSize: 10, expectedLen: 1},
+		{name: "startIndex greater than length of the array", startIndex: 2, pageSize: 10},
+		{name: "pageIndex is less than the length", startIndex: 0, pageSize: 1, expectedLen: 1},
+		{name: "pageIndex and startIndex is less than the length", startIndex: 1, pageSize: 1, expectedLen: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results := c.GetItemsWithKeys(test.pageSize, test.startIndex)
+			assert.Len(t, results, test.expectedLen)
+			assert.Len(t, c.GetItems(test.pageSize, test.startIndex), test.expectedLen)
+			for _, item := range results {
+				assert.Equal(t, c.ItemByKey(item.Key), item.Contents)
+			}
+		})
+	}
+	assert.ElementsMatch(t, []KeyedItem{{Key: 0, Contents: "0"}, {Key: 1, Contents: "1"}}, c.GetItemsWithKeys(10, 0))
+}
+
+func TestCollection_Keys(t *testing.T) {
+	c := NewCollection()
+	assert.Empty(t, c.Keys())
+	c.Set(0, "0", cell1.lat, cell1.lon)
+	c.Set("1", "1", cell2.lat, cell2.lon)
+	keys := c.Keys()
+	assert.ElementsMatch(t, []interface{}{0, "1"}, keys)
+
+	keys[0] = 2
+	assert.ElementsMatch(t, []interface{}{0, "1"}, c.Keys())
+}
+
+func TestCollection_ForEach(t *testing.T) {
+	c := NewCollection()
+	c.Set(0, "0", cell1.lat, cell1.lon)
+	c.Set(1, "1", cell2.lat, cell2.lon)
+	c.Set(2, "2", 0, 0)
+
+	visited := make(map[interface{}]testItem)
+	c.ForEach(func(key, contents interface{}, latitude, longitude float64) bool {
+		visited[key] = testItem{key: key.(int), contents: contents.(string), lat: latitude, lon: longitude}
+		return true
+	})
+	assert.Equal(t, map[interface{}]testItem{
+		0: {key: 0, contents: "0", lat: cell1.lat, lon: cell1.lon},
+		1: {key: 1, contents: "1", lat: cell2.lat, lon: cell2.lon},
+		2: {key: 2, contents: "2"},
+	}, visited)
+
+	calls := 0
+	c.ForEach(func(interface{}, interface{}, float64, float64) bool {
+		calls++
+		return calls < 2
+	})
+	assert.Equal(t, 2, calls)
+}
+
+func TestCollection_All(t *testing.T) {
+	c := NewCollection()
+	c.Set(0, "0", cell1.lat, cell1.lon)
+	c.Set(1, "1", cell2.lat, cell2.lon)
+	c.Set(2, "2", 0, 0)
+
+	visited := make(map[interface{}]interface{})
+	for key, contents := range c.All() {
+		visited[key] = contents
+	}
+	assert.Equal(t, map[interface{}]interface{}{0: "0", 1: "1", 2: "2"}, visited)
+
+	// breaking out of the loop releases the read lock
+	for range c.All() {
+		break
+	}
+	c.Set(3, "3", 0, 0)
+	assert.Equal(t, 4, c.Count())
+}
+
+func TestCollection_Count(t *testing.T) {
+	cl := NewCollection()
+	assert.Zero(t, cl.Count())
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell2.lat, cell2.lon)
+	assert.Equal(t, 2, cl.Count())
+	cl.Delete(0)
+	assert.Equal(t, 1, cl.Count())
+}
+
+func TestCollection_PeakLen(t *testing.T) {
+	cl := NewCollection()
+	assert.Zero(t, cl.PeakLen())
+	for i := 0; i < 5; i++ {
+		cl.Set(i, i, cell1.lat, cell1.lon)
+	}
+	// updating an item does not change the number of items
+	cl.Set(0, 0, cell2.lat, cell2.lon)
+	for i := 0; i < 3; i++ {
+		cl.Delete(i)
+	}
+	assert.Equal(t, 5, cl.PeakLen())
+
+	cl.ResetPeak()
+	assert.Equal(t, 2, cl.PeakLen())
+	cl.Set(10, 10, cell1.lat, cell1.lon)
+	assert.Equal(t, 3, cl.PeakLen())
+}
+
+// marsRadiusMeters is the mean radius of Mars
+const marsRadiusMeters = 3389500.0
+
+func TestNewCollectionWithRadius(t *testing.T) {
+	assert.Equal(t, EarthRadiusMeters, NewCollection().Radius())
+	assert.Equal(t, EarthRadiusMeters, NewCollectionWithRadius(0).Radius())
+	assert.Equal(t, EarthRadiusMeters, NewCollectionWithRadius(math.NaN()).Radius())
+
+	earth, mars := NewCollection(), NewCollectionWithRadius(marsRadiusMeters, WithUpdateTimestamps())
+	assert.Equal(t, marsRadiusMeters, mars.Radius())
+	assert.True(t, mars.trackUpdates)
+	for _, cl := range []Collection{earth, mars} {
+		cl.Set(0, "center", 0, 0)
+		// about 1112m away on the Earth and 592m away on Mars
+		cl.Set(1, "north", 0.01, 0)
+	}
+	expectedMeters := 0.01 * math.Pi / 180 * marsRadiusMeters
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 30, LevelMod: 1, MaxCells: 8}
+
+	found, _ := mars.ItemsWithinDistanceWithMeta(0, 0, 800, params)
+	require.Len(t, found, 2)
+	for _, item := range found {
+		if item.Key == 1 {
+			assert.InDelta(t, expectedMeters, item.DistanceMeters, 1e-6)
+		}
+	}
+	found, _ = earth.ItemsWithinDistanceWithMeta(0, 0, 800, params)
+	assert.Len(t, found, 1)
+
+	marsItems, _ := mars.ItemsWithinDistance(0.01, 0, 50, params)
+	assert.Equal(t, []interface{}{"north"}, marsItems)
+	nearest := mars.KNearestExcluding(0, 0, 2, nil)
+	require.Len(t, nearest, 2)
+	assert.InDelta(t, expectedMeters, nearest[1].DistanceMeters, 1e-6)
+	assert.Equal(t, marsRadiusMeters, mars.FrozenSnapshot().Radius())
+}
+
 func TestEarthDistanceMeters(t *testing.T) {
 	// pick 2 points off a map that are roughly 105 meters of each other
 	p1 := NewPointFromLatLng(41.883170, -87.632278)
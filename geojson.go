@@ -0,0 +1,197 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// geoJSONFeatureCollection is a GeoJSON FeatureCollection
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// geoJSONFeature is a GeoJSON Feature, whose geometry is a geoJSONPoint or a geoJSONPolygon
+type geoJSONFeature struct {
+	Type       string          `json:"type"`
+	Geometry   interface{}     `json:"geometry"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+// geoJSONPoint is a GeoJSON Point geometry, whose coordinates are a longitude and a latitude
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+// geoJSONPolygon is a GeoJSON Polygon geometry, whose coordinates are closed rings of longitude and latitude pairs
+type geoJSONPolygon struct {
+	Type        string        `json:"type"`
+	Coordinates [][][]float64 `json:"coordinates"`
+}
+
+// geoJSONProperties are the properties of the feature of an item
+type geoJSONProperties struct {
+	Key      interface{} `json:"key"`
+	Contents interface{} `json:"contents"`
+	Heading  *float64    `json:"heading,omitempty"`
+}
+
+// MarshalGeoJSON encodes the collection as a GeoJSON FeatureCollection with one Point feature per item that has not
+// expired, ordered by key, for inspection in GIS tools. Each feature's geometry is the item's stored coordinates,
+// and its properties hold the item's key, its contents and, for items that have one, its heading. Keys and
+// contents must be serializable with encoding/json; the error returned otherwise names the key of the offending
+// item.
+func (c Collection) MarshalGeoJSON() ([]byte, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	keys := c.liveKeys()
+	sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+
+	features := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, 0, len(keys))}
+	for _, key := range keys {
+		item := c.items[key]
+		properties := geoJSONProperties{Key: key, Contents: item.contents}
+		if item.hasHeading {
+			properties.Heading = &item.heading
+		}
+		encoded, err := json.Marshal(properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode item with key %v as GeoJSON: %w", key, err)
+		}
+		features.Features = append(features.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONPoint{Type: "Point", Coordinates: [2]float64{item.longitude, item.latitude}},
+			Properties: encoded,
+		})
+	}
+	return json.Marshal(features)
+}
+
+// GeoJSON encodes the covering as a GeoJSON FeatureCollection with one Polygon feature per polygon of the covering,
+// in the same order, so that it can be drawn without converting it first. Each polygon is one covering cell, or one
+// outline for coverings merged with MergeCovering, and its coordinates are the same longitude and latitude pairs.
+// The features have no properties. An error is returned if a coordinate cannot be encoded, such as a NaN.
+func (r SearchCoveringResult) GeoJSON() ([]byte, error) {
+	features := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, 0, len(r))}
+	for _, ring := range r {
+		features.Features = append(features.Features, geoJSONFeature{
+			Type:       "Feature",
+			Geometry:   geoJSONPolygon{Type: "Polygon", Coordinates: [][][]float64{ring}},
+			Properties: json.RawMessage("{}"),
+		})
+	}
+	return json.Marshal(features)
+}
+
+// geoJSONInputFeature is a GeoJSON Feature as read by LoadGeoJSON, which leaves decoding its geometry's coordinates
+// until the type of the geometry is known
+type geoJSONInputFeature struct {
+	Geometry *struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	} `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// LoadGeoJSON creates a new collection from a GeoJSON FeatureCollection, setting an item for every Point feature.
+// Each item's key is returned by keyFromFeature, which is called with the feature's properties, and its contents
+// are the properties themselves, decoded into the generic types of encoding/json. Features with the same key
+// replace those before them. Features whose geometry is not a Point, including features without a geometry, are
+// skipped; use LoadGeoJSONStrict to reject them instead. Use LoadMarshaledGeoJSON to load collections written by
+// MarshalGeoJSON back with their original contents. An error wrapping ErrInvalidGeoJSON is returned if the data is
+// not a FeatureCollection, and one wrapping ErrInvalidCoordinate if a Point is out of range.
+func LoadGeoJSON(data []byte, keyFromFeature func(map[string]interface{}) interface{}) (Collection, error) {
+	return loadGeoJSON(data, false, setFeature(keyFromFeature))
+}
+
+// LoadGeoJSONStrict performs the same load as LoadGeoJSON, but returns an error wrapping ErrInvalidGeoJSON
+// instead of skipping features whose geometry is not a Point.
+func LoadGeoJSONStrict(data []byte, keyFromFeature func(map[string]interface{}) interface{}) (Collection, error) {
+	return loadGeoJSON(data, true, setFeature(keyFromFeature))
+}
+
+// LoadMarshaledGeoJSON creates a new collection from GeoJSON written by MarshalGeoJSON, undoing its encoding: each
+// item's key and contents are the "key" and "contents" properties of its feature, and a "heading" property is set
+// as its heading. Keys and contents are decoded into the generic types of encoding/json, so numeric keys come back
+// as float64. Features that are not Points or lack a "key" or "contents" property return an error wrapping
+// ErrInvalidGeoJSON, and features with the same key replace those before them.
+func LoadMarshaledGeoJSON(data []byte) (Collection, error) {
+	return loadGeoJSON(data, true, func(c Collection, i int, properties map[string]interface{}, lat, lon float64) error {
+		key, hasKey := properties["key"]
+		contents, hasContents := properties["contents"]
+		if !hasKey || !hasContents {
+			return fmt.Errorf("%w: feature %d lacks a key or contents property", ErrInvalidGeoJSON, i)
+		}
+		if heading, ok := properties["heading"].(float64); ok {
+			c.SetWithHeading(key, contents, lat, lon, heading)
+			return nil
+		}
+		c.Set(key, contents, lat, lon)
+		return nil
+	})
+}
+
+// setFeature returns the setter of LoadGeoJSON, which stores the properties of each feature as its contents
+func setFeature(
+	keyFromFeature func(map[string]interface{}) interface{},
+) func(c Collection, i int, properties map[string]interface{}, lat, lon float64) error {
+	return func(c Collection, _ int, properties map[string]interface{}, lat, lon float64) error {
+		c.Set(keyFromFeature(properties), properties, lat, lon)
+		return nil
+	}
+}
+
+// loadGeoJSON loads a GeoJSON FeatureCollection into a new collection, storing each Point feature with set and
+// returning an error for features that are not Points when strict is set and skipping them otherwise
+func loadGeoJSON(
+	data []byte, strict bool, set func(c Collection, i int, properties map[string]interface{}, lat, lon float64) error,
+) (Collection, error) {
+	var features struct {
+		Type     string                `json:"type"`
+		Features []geoJSONInputFeature `json:"features"`
+	}
+	if err := json.Unmarshal(data, &features); err != nil {
+		return Collection{}, fmt.Errorf("%w: %w", ErrInvalidGeoJSON, err)
+	}
+	if features.Type != "FeatureCollection" {
+		return Collection{}, fmt.Errorf("%w: type %q is not FeatureCollection", ErrInvalidGeoJSON, features.Type)
+	}
+	c := NewCollection()
+	for i, feature := range features.Features {
+		if feature.Geometry == nil || feature.Geometry.Type != "Point" {
+			if strict {
+				return Collection{}, fmt.Errorf("%w: feature %d is not a Point", ErrInvalidGeoJSON, i)
+			}
+			continue
+		}
+		// positions may have an altitude after the longitude and latitude, which is ignored
+		var position []float64
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &position); err != nil || len(position) < 2 {
+			return Collection{}, fmt.Errorf("%w: feature %d has invalid Point coordinates", ErrInvalidGeoJSON, i)
+		}
+		longitude, latitude := position[0], position[1]
+		if err := validateSearch(latitude, longitude, 0); err != nil {
+			return Collection{}, fmt.Errorf("feature %d: %w", i, err)
+		}
+		if err := set(c, i, feature.Properties, latitude, longitude); err != nil {
+			return Collection{}, err
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,247 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollection_MarshalGeoJSON(t *testing.T) {
+	cl := NewCollection()
+	cl.Set("b", map[string]interface{}{"name": "b"}, cell2.lat, cell2.lon)
+	cl.SetWithHeading("a", "a", cell1.lat, cell1.lon, 90)
+
+	encoded, err := cl.MarshalGeoJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "FeatureCollection",
+		"features": [
+			{
+				"type": "Feature",
+				"geometry": {"type": "Point", "coordinates": [-87.63028184499035, 41.87963549397698]},
+				"properties": {"key": "a", "contents": "a", "heading": 90}
+			},
+			{
+				"type": "Feature",
+				"geometry": {"type": "Point", "coordinates": [-73.98119781456353, 40.75306726395187]},
+				"properties": {"key": "b", "contents": {"name": "b"}}
+			}
+		]
+	}`, string(encoded))
+
+	empty, err := NewCollection().MarshalGeoJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, string(empty))
+
+	cl.Set("channel", make(chan int), cell1.lat, cell1.lon)
+	_, err = cl.MarshalGeoJSON()
+	assert.ErrorContains(t, err, "key channel")
+}
+
+func TestSearchCoveringResult_GeoJSON(t *testing.T) {
+	covering := SearchCoveringResult{
+		{{0, 0}, {1, 0}, {1, 1}, {0, 1}, {0, 0}},
+		{{-87.6, 41.8}, {-87.5, 41.8}, {-87.5, 41.9}, {-87.6, 41.8}},
+	}
+	encoded, err := covering.GeoJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "FeatureCollection",
+		"features": [
+			{
+				"type": "Feature",
+				"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1], [0, 0]]]},
+				"properties": {}
+			},
+			{
+				"type": "Feature",
+				"geometry": {
+					"type": "Polygon",
+					"coordinates": [[[-87.6, 41.8], [-87.5, 41.8], [-87.5, 41.9], [-87.6, 41.8]]]
+				},
+				"properties": {}
+			}
+		]
+	}`, string(encoded))
+
+	// a search's covering is encoded one polygon per cell
+	cl := NewCollection()
+	_, searchCovering := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, SearchCoveringParameters{
+		MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8})
+	encoded, err = searchCovering.GeoJSON()
+	require.NoError(t, err)
+	var decoded struct {
+		Features []struct {
+			Geometry struct {
+				Coordinates [][][]float64
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Len(t, decoded.Features, len(searchCovering))
+	for i, feature := range decoded.Features {
+		assert.Equal(t, [][][]float64{searchCovering[i]}, feature.Geometry.Coordinates)
+	}
+
+	empty, err := SearchCoveringResult(nil).GeoJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, string(empty))
+
+	_, err = SearchCoveringResult{{{math.NaN(), 0}}}.GeoJSON()
+	assert.Error(t, err)
+}
+
+func TestLoadGeoJSON(t *testing.T) {
+	byName := func(properties map[string]interface{}) interface{} { return properties["name"] }
+	tests := []struct {
+		name        string
+		data        string
+		strict      bool
+		expected    map[interface{}]interface{}
+		expectedErr error
+	}{
+		{
+			name: "point features are set",
+			data: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-87.6, 41.9]},
+					"properties": {"name": "a", "spots": 3}},
+				{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-74, 40.8, 10]},
+					"properties": {"name": "b"}}
+			]}`,
+			expected: map[interface{}]interface{}{
+				"a": map[string]interface{}{"name": "a", "spots": float64(3)},
+				"b": map[string]interface{}{"name": "b"},
+			},
+		}, {
+			name: "properties named like those of MarshalGeoJSON are kept",
+			data: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-87.6, 41.9]},
+					"properties": {"name": "a", "contents": "spots", "heading": 90}}
+			]}`,
+			expected: map[interface{}]interface{}{
+				"a": map[string]interface{}{"name": "a", "contents": "spots", "heading": float64(90)},
+			},
+		}, {
+			name: "other geometries are skipped",
+			data: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "geometry": {"type": "Point", "coordinates": [-87.6, 41.9]},
+					"properties": {"name": "a"}},
+				{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]},
+					"properties": {"name": "b"}},
+				{"type": "Feature", "geometry": null, "properties": {"name": "c"}}
+			]}`,
+			expected: map[interface{}]interface{}{"a": map[string]interface{}{"name": "a"}},
+		}, {
+			name: "other geometries are rejected when strict",
+			data: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "geometry": {"type": "LineString", "coordinates": [[0, 0], [1, 1]]},
+					"properties": {"name": "b"}}
+			]}`,
+			strict:      true,
+			expectedErr: ErrInvalidGeoJSON,
+		}, {
+			name:     "empty feature collections",
+			data:     `{"type": "FeatureCollection", "features": []}`,
+			expected: map[interface{}]interface{}{},
+		}, {
+			name:        "other GeoJSON types are rejected",
+			data:        `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 0]}}`,
+			expectedErr: ErrInvalidGeoJSON,
+		}, {
+			name:        "malformed JSON is rejected",
+			data:        `{"type": "FeatureCollection", "features": [`,
+			expectedErr: ErrInvalidGeoJSON,
+		}, {
+			name: "points without a latitude are rejected",
+			data: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0]}, "properties": {}}
+			]}`,
+			expectedErr: ErrInvalidGeoJSON,
+		}, {
+			name: "points out of range are rejected",
+			data: `{"type": "FeatureCollection", "features": [
+				{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 91]}, "properties": {}}
+			]}`,
+			expectedErr: ErrInvalidCoordinate,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			load := LoadGeoJSON
+			if test.strict {
+				load = LoadGeoJSONStrict
+			}
+			cl, err := load([]byte(test.data), byName)
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, len(test.expected), cl.Count())
+			for key, contents := range test.expected {
+				assert.Equal(t, contents, cl.ItemByKey(key))
+			}
+		})
+	}
+}
+
+func TestLoadMarshaledGeoJSON(t *testing.T) {
+	cl := NewCollection()
+	cl.Set("a", "a", cell1.lat, cell1.lon)
+	cl.SetWithHeading("b", map[string]interface{}{"name": "b"}, cell2.lat, cell2.lon, 90)
+	encoded, err := cl.MarshalGeoJSON()
+	require.NoError(t, err)
+
+	loaded, err := LoadMarshaledGeoJSON(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, "a", loaded.ItemByKey("a"))
+	assert.Equal(t, map[string]interface{}{"name": "b"}, loaded.ItemByKey("b"))
+	lat, lon, ok := loaded.GetLocation("b")
+	require.True(t, ok)
+	assert.InDelta(t, cell2.lat, lat, 1e-9)
+	assert.InDelta(t, cell2.lon, lon, 1e-9)
+	assert.False(t, loaded.items["a"].hasHeading)
+	assert.True(t, loaded.items["b"].hasHeading)
+	assert.InDelta(t, 90, loaded.items["b"].heading, 1e-9)
+
+	// exporting the loaded collection again gives the same GeoJSON
+	reencoded, err := loaded.MarshalGeoJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(encoded), string(reencoded))
+}
+
+func TestLoadMarshaledGeoJSON_errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"features without contents": `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 0]}, "properties": {"key": "a"}}
+		]}`,
+		"features without a key": `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 0]}, "properties": {"contents": "a"}}
+		]}`,
+		"features that are not points": `{"type": "FeatureCollection", "features": [
+			{"type": "Feature", "geometry": null, "properties": {"key": "a", "contents": "a"}}
+		]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadMarshaledGeoJSON([]byte(data))
+			assert.ErrorIs(t, err, ErrInvalidGeoJSON)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s2"
+)
+
+// BoundingCap returns a spherical cap containing every item in the collection as its center and radius. The cap
+// is centered on the centroid of the items' locations, which for items clustered in one area is close to the
+// smallest enclosing cap but is not guaranteed to be it. A collection with a single item returns a radius of
+// zero. ok is false when the collection is empty.
+func (c Collection) BoundingCap() (centerLat, centerLon, radiusMeters float64, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := c.now()
+	points := make([]s2.Point, 0, len(c.items))
+	var sum r3.Vector
+	for _, item := range c.items {
+		if item.expired(now) {
+			continue
+		}
+		point := item.point
+		points = append(points, point)
+		sum = sum.Add(point.Vector)
+	}
+	if len(points) == 0 {
+		return 0, 0, 0, false
+	}
+	center := points[0]
+	if sum.Norm() > 0 {
+		center = s2.Point{Vector: sum.Normalize()}
+	}
+	for _, point := range points {
+		radiusMeters = max(radiusMeters, c.distance(center, point))
+	}
+	ll := s2.LatLngFromPoint(center)
+	return ll.Lat.Degrees(), ll.Lng.Degrees(), radiusMeters, true
+}
+
+// WeightedCentroid returns the centroid of the items in the collection with each item weighted by the result of
+// calling weight with its contents. Locations are averaged as points on the sphere rather than as latitudes and
+// longitudes, so the result is sensible near the poles and the antimeridian. Weights should not be negative. ok is
+// false when the collection is empty or the total weight is zero.
+func (c Collection) WeightedCentroid(weight func(contents interface{}) float64) (lat, lon float64, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	now := c.now()
+	var sum r3.Vector
+	totalWeight := 0.0
+	for _, item := range c.items {
+		if item.expired(now) {
+			continue
+		}
+		w := weight(item.contents)
+		totalWeight += w
+		sum = sum.Add(item.point.Mul(w))
+	}
+	if totalWeight == 0 || sum.Norm() == 0 {
+		return 0, 0, false
+	}
+	ll := s2.LatLngFromPoint(s2.Point{Vector: sum.Normalize()})
+	return ll.Lat.Degrees(), ll.Lng.Degrees(), true
+}
@@ -0,0 +1,126 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_BoundingCap(t *testing.T) {
+	chicago := NewPointFromLatLng(cell1.lat, cell1.lon)
+	manhattan := NewPointFromLatLng(cell2.lat, cell2.lon)
+	midpoint := s2.LatLngFromPoint(s2.Interpolate(0.5, chicago, manhattan))
+	tests := []struct {
+		name           string
+		items          []testItem
+		expectedLat    float64
+		expectedLon    float64
+		expectedRadius float64
+		expectedOK     bool
+	}{
+		{
+			name:       "Empty collection is not ok",
+			expectedOK: false,
+		}, {
+			name:        "Single item has a zero radius",
+			items:       []testItem{{key: 0, lat: cell1.lat, lon: cell1.lon}},
+			expectedLat: cell1.lat,
+			expectedLon: cell1.lon,
+			expectedOK:  true,
+		}, {
+			name: "Two items are bounded by the cap around their midpoint",
+			items: []testItem{
+				{key: 0, lat: cell1.lat, lon: cell1.lon},
+				{key: 1, lat: cell2.lat, lon: cell2.lon},
+			},
+			expectedLat:    midpoint.Lat.Degrees(),
+			expectedLon:    midpoint.Lng.Degrees(),
+			expectedRadius: EarthDistanceMeters(chicago, manhattan) / 2,
+			expectedOK:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := NewCollection()
+			for _, item := range test.items {
+				cl.Set(item.key, item.contents, item.lat, item.lon)
+			}
+			lat, lon, radius, ok := cl.BoundingCap()
+			assert.Equal(t, test.expectedOK, ok)
+			assert.InDelta(t, test.expectedLat, lat, 1e-9)
+			assert.InDelta(t, test.expectedLon, lon, 1e-9)
+			assert.InDelta(t, test.expectedRadius, radius, 1)
+		})
+	}
+}
+
+func TestCollection_WeightedCentroid(t *testing.T) {
+	spots := func(contents interface{}) float64 { return contents.(float64) }
+	chicago := NewPointFromLatLng(cell1.lat, cell1.lon)
+	manhattan := NewPointFromLatLng(cell2.lat, cell2.lon)
+	tests := []struct {
+		name       string
+		items      []testItem
+		weights    []float64
+		expectedOK bool
+		// expectedFraction is how far along the path from Chicago to Manhattan the centroid should be
+		expectedFraction float64
+	}{
+		{name: "Empty collection is not ok"},
+		{
+			name:    "Zero total weight is not ok",
+			items:   []testItem{{key: 0, lat: cell1.lat, lon: cell1.lon}},
+			weights: []float64{0},
+		}, {
+			name: "Equal weights give the midpoint",
+			items: []testItem{
+				{key: 0, lat: cell1.lat, lon: cell1.lon},
+				{key: 1, lat: cell2.lat, lon: cell2.lon},
+			},
+			weights:          []float64{1, 1},
+			expectedOK:       true,
+			expectedFraction: 0.5,
+		}, {
+			name: "Heavily weighted cluster pulls the centroid towards it",
+			items: []testItem{
+				{key: 0, lat: cell1.lat, lon: cell1.lon},
+				{key: 1, lat: cell2.lat, lon: cell2.lon},
+			},
+			weights:          []float64{1, 99},
+			expectedOK:       true,
+			expectedFraction: 0.99,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := NewCollection()
+			for i, item := range test.items {
+				cl.Set(item.key, test.weights[i], item.lat, item.lon)
+			}
+			lat, lon, ok := cl.WeightedCentroid(spots)
+			assert.Equal(t, test.expectedOK, ok)
+			if !ok {
+				return
+			}
+			centroid := NewPointFromLatLng(lat, lon)
+			total := EarthDistanceMeters(chicago, manhattan)
+			assert.InDelta(t, test.expectedFraction, EarthDistanceMeters(chicago, centroid)/total, 0.001)
+			assert.InDelta(t, 1-test.expectedFraction, EarthDistanceMeters(centroid, manhattan)/total, 0.001)
+		})
+	}
+}
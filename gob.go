@@ -0,0 +1,143 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// gobVersion is the version of the gob encoding of a collection, written with every snapshot so that the
+// encoding can change without misreading older snapshots. Gob skips fields it doesn't know, so snapshots of later
+// versions that only add fields still decode.
+const gobVersion = 1
+
+// minGobVersion is the oldest version of the gob encoding that GobDecode can read
+const minGobVersion = 1
+
+// gobSnapshot is the gob encoding of a collection
+type gobSnapshot struct {
+	Version int
+	// RadiusMeters is the radius of the sphere the collection measures distances on
+	RadiusMeters float64
+	Items        []gobItem
+}
+
+// gobItem is the gob encoding of a single stored item. Cell is the item's cell; the rest of the index is derived
+// from it when the item is decoded.
+type gobItem struct {
+	Key        interface{}
+	Contents   interface{}
+	Latitude   float64
+	Longitude  float64
+	Cell       uint64
+	Heading    float64
+	HasHeading bool
+	UpdatedAt  time.Time
+	// ExpiresAt is zero for items that never expire. Snapshots from before expiry was encoded decode with it
+	// zero, so the version is unchanged.
+	ExpiresAt time.Time
+}
+
+// GobEncode implements gob.GobEncoder, encoding every item in the collection along with the cell it is indexed
+// from, its heading, the time it was last set and its expiry. The rest of the index is rebuilt from the cells on
+// decode rather than stored. Options such as the clock, contents copier, WAL and index levels are not encoded.
+// Keys and contents are encoded as interface values, so callers must gob.Register every concrete key and contents
+// type other than the basic types before encoding or decoding a collection.
+func (c Collection) GobEncode() ([]byte, error) {
+	c.mutex.RLock()
+	snapshot := gobSnapshot{
+		Version:      gobVersion,
+		RadiusMeters: c.radiusMeters,
+		Items:        make([]gobItem, 0, len(c.items)),
+	}
+	for key, item := range c.items {
+		snapshot.Items = append(snapshot.Items, gobItem{
+			Key:        key,
+			Contents:   item.contents,
+			Latitude:   item.latitude,
+			Longitude:  item.longitude,
+			Cell:       uint64(item.cellID),
+			Heading:    item.heading,
+			HasHeading: item.hasHeading,
+			UpdatedAt:  item.updatedAt,
+			ExpiresAt:  item.expiresAt,
+		})
+	}
+	c.mutex.RUnlock()
+	// encode the items in key order so that the same collection always encodes the same way
+	sort.Slice(snapshot.Items, func(i, j int) bool {
+		return compareKeys(snapshot.Items[i].Key, snapshot.Items[j].Key) < 0
+	})
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to encode collection: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GobDecode implements gob.GobDecoder, replacing the items of the collection with those encoded by GobEncode and
+// rebuilding their index. Decoding into the zero Collection initializes it as NewCollectionWithRadius does with
+// the radius of the encoded collection, while decoding into a collection created with options keeps them, its
+// radius and its index levels, so a collection can be restored with its clock, copier or WAL in place. Contents
+// are stored as decoded rather than copied. Snapshots without a version fail to decode, as do snapshots with items
+// set with SetToken whose cell is coarser than the indexed levels.
+func (c *Collection) GobDecode(data []byte) error {
+	var snapshot gobSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode collection: %w", err)
+	}
+	if snapshot.Version < minGobVersion {
+		return fmt.Errorf("failed to decode collection: unsupported version %d", snapshot.Version)
+	}
+	for _, item := range snapshot.Items {
+		if !s2.CellID(item.Cell).IsValid() {
+			return fmt.Errorf("failed to decode collection: key %v has invalid cell %d", item.Key, item.Cell)
+		}
+	}
+	if c.mutex == nil {
+		*c = NewCollectionWithRadius(snapshot.RadiusMeters)
+	}
+	for _, item := range snapshot.Items {
+		if level := s2.CellID(item.Cell).Level(); level < c.minIndexLevel {
+			return fmt.Errorf("failed to decode collection: key %v has a cell of level %d, coarser than the indexed levels",
+				item.Key, level)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.removeAll()
+	for _, item := range snapshot.Items {
+		c.insert(item.Key, collectionContents{
+			contents:   item.Contents,
+			updatedAt:  item.UpdatedAt,
+			latitude:   item.Latitude,
+			longitude:  item.Longitude,
+			point:      NewPointFromLatLng(item.Latitude, item.Longitude),
+			cellID:     s2.CellID(item.Cell),
+			heading:    item.Heading,
+			hasHeading: item.HasHeading,
+			expiresAt:  item.ExpiresAt,
+		})
+	}
+	return nil
+}
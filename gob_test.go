@@ -0,0 +1,131 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type gobTestContents struct {
+	Name string
+}
+
+func TestCollection_Gob(t *testing.T) {
+	gob.Register(gobTestContents{})
+	updatedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	cl, _ := randomCollection(200)
+	cl.now = func() time.Time { return updatedAt }
+	cl.Set("struct", gobTestContents{Name: "struct"}, cell2.lat, cell2.lon)
+	cl.SetWithHeading("heading", "heading", cell2.lat, cell2.lon, 90)
+	require.NoError(t, cl.SetToken("token", "token", cell2.cellID.Parent(12).ToToken()))
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(cl))
+	encoded := append([]byte(nil), buf.Bytes()...)
+	var decoded Collection
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+
+	assert.Equal(t, cl.items, decoded.items)
+	assert.Equal(t, cl.keys, decoded.keys)
+	assert.Equal(t, cl.cells, decoded.cells)
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 16, LevelMod: 1, MaxCells: 8, SortByDistance: true}
+	for _, search := range []struct{ lat, lon, distance float64 }{
+		{cell1.lat, cell1.lon, 1000},
+		{cell1.lat, cell1.lon, 20000},
+		{cell2.lat, cell2.lon, 100000},
+	} {
+		expected, expectedCovering := cl.ItemsWithinDistance(search.lat, search.lon, search.distance, params)
+		found, covering := decoded.ItemsWithinDistance(search.lat, search.lon, search.distance, params)
+		assert.Equal(t, expected, found)
+		assert.Equal(t, expectedCovering, covering)
+	}
+
+	// the same collection always encodes the same way
+	buf.Reset()
+	require.NoError(t, gob.NewEncoder(&buf).Encode(decoded))
+	assert.Equal(t, encoded, buf.Bytes())
+
+	// decoding into a collection replaces its items and keeps its options
+	copies := 0
+	into := NewCollection(WithContentsCopier(func(contents interface{}) interface{} {
+		copies++
+		return contents
+	}))
+	into.Set("stale", "stale", 0, 0)
+	require.NoError(t, gob.NewDecoder(bytes.NewReader(encoded)).Decode(&into))
+	assert.False(t, into.Has("stale"))
+	assert.Equal(t, cl.Count(), into.Count())
+	copies = 0
+	assert.Equal(t, gobTestContents{Name: "struct"}, into.ItemByKey("struct"))
+	assert.Equal(t, 1, copies)
+}
+
+func TestCollection_GobDecode_errors(t *testing.T) {
+	var cl Collection
+	assert.Error(t, cl.GobDecode([]byte("not gob")))
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(gobSnapshot{Version: 0}))
+	assert.ErrorContains(t, cl.GobDecode(buf.Bytes()), "unsupported version")
+
+	buf.Reset()
+	require.NoError(t, gob.NewEncoder(&buf).Encode(gobSnapshot{Version: gobVersion, Items: []gobItem{{Key: 0}}}))
+	assert.ErrorContains(t, cl.GobDecode(buf.Bytes()), "invalid cell")
+	assert.Nil(t, cl.items)
+
+	// decoding into the zero Collection keeps the radius of the encoded collection
+	encoded, err := NewCollectionWithRadius(marsRadiusMeters).GobEncode()
+	require.NoError(t, err)
+	var mars Collection
+	require.NoError(t, mars.GobDecode(encoded))
+	assert.Equal(t, marsRadiusMeters, mars.Radius())
+
+	// contents of types that are not registered cannot be encoded
+	unregistered := NewCollection()
+	unregistered.Set(0, struct{ Name string }{"0"}, cell1.lat, cell1.lon)
+	_, err = unregistered.GobEncode()
+	assert.Error(t, err)
+}
+
+func TestCollection_GobDecode_newerVersion(t *testing.T) {
+	// a snapshot of a later version with a field this version doesn't know about
+	type newerSnapshot struct {
+		Version      int
+		RadiusMeters float64
+		Items        []gobItem
+		Extra        string
+	}
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(newerSnapshot{
+		Version:      gobVersion + 1,
+		RadiusMeters: EarthRadiusMeters,
+		Items: []gobItem{{
+			Key: "a", Contents: "a", Latitude: cell1.lat, Longitude: cell1.lon, Cell: uint64(cell1.cellID),
+		}},
+		Extra: "extra",
+	}))
+
+	var cl Collection
+	require.NoError(t, cl.GobDecode(buf.Bytes()))
+	assert.Equal(t, "a", cl.ItemByKey("a"))
+	assert.Equal(t, []interface{}{"a"}, cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 100, SearchCoveringParameters{
+		MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}))
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math"
+)
+
+// SetWithHeading adds or updates an item like Set and also stores its heading, the bearing it is facing or moving
+// in, as degrees clockwise from north. Headings outside [0, 360) are wrapped into that range. Setting the item
+// again with Set clears its heading.
+func (c Collection) SetWithHeading(key, contents interface{}, latitude, longitude, headingDegrees float64) {
+	item := c.newContents(contents, latitude, longitude)
+	item.heading, item.hasHeading = wrapDegrees(headingDegrees), true
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.store(key, item)
+}
+
+// SearchFilter limits the items returned by ItemsWithinDistanceFiltered. The zero SearchFilter matches every item.
+type SearchFilter struct {
+	match func(item collectionContents) bool
+}
+
+// matches reports whether the filter matches item
+func (f SearchFilter) matches(item collectionContents) bool {
+	return f.match == nil || f.match(item)
+}
+
+// HeadingWithin matches items with a heading no more than toleranceDegrees from the target bearing in either
+// direction, accounting for headings that wrap around north. Items stored without a heading never match.
+func HeadingWithin(targetDegrees, toleranceDegrees float64) SearchFilter {
+	target := wrapDegrees(targetDegrees)
+	return SearchFilter{match: func(item collectionContents) bool {
+		if !item.hasHeading {
+			return false
+		}
+		difference := math.Abs(item.heading - target)
+		return math.Min(difference, 360-difference) <= toleranceDegrees
+	}}
+}
+
+// ItemsWithinDistanceFiltered performs the same search as ItemsWithinDistance but only returns the items that
+// match every one of the filters.
+func (c Collection) ItemsWithinDistanceFiltered(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters, filters ...SearchFilter,
+) ([]interface{}, SearchCoveringResult) {
+	if validateSearch(latitude, longitude, distanceMeters) != nil {
+		return []interface{}{}, SearchCoveringResult{}
+	}
+	center := NewPointFromLatLng(latitude, longitude)
+	cellUnion := params.covering(params.searchCap(center, distanceMeters, c.radiusMeters))
+	cellBounds := coveringResult(cellUnion, params.MergeCovering)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	foundItems := make([]interface{}, 0)
+	c.eachInCovering(cellUnion, func(_ interface{}, item collectionContents) bool {
+		for _, filter := range filters {
+			if !filter.matches(item) {
+				return true
+			}
+		}
+		foundItems = append(foundItems, c.copied(item.contents))
+		return true
+	})
+	return foundItems, cellBounds
+}
+
+// wrapDegrees wraps an angle in degrees into [0, 360)
+func wrapDegrees(degrees float64) float64 {
+	wrapped := math.Mod(degrees, 360)
+	if wrapped < 0 {
+		wrapped += 360
+	}
+	// adding a full turn to a tiny negative angle rounds to 360
+	if wrapped >= 360 {
+		return 0
+	}
+	return wrapped
+}
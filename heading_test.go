@@ -0,0 +1,89 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeadingWithin(t *testing.T) {
+	tests := []struct {
+		name       string
+		heading    float64
+		hasHeading bool
+		target     float64
+		tolerance  float64
+		expected   bool
+	}{
+		{name: "Headings within tolerance match", heading: 95, hasHeading: true, target: 90, tolerance: 10, expected: true},
+		{name: "Headings outside tolerance do not match", heading: 110, hasHeading: true, target: 90, tolerance: 10},
+		{name: "Headings wrap past north", heading: 5, hasHeading: true, target: 350, tolerance: 20, expected: true},
+		{name: "Targets wrap past north", heading: 350, hasHeading: true, target: 5, tolerance: 20, expected: true},
+		{name: "Targets outside of a full turn are wrapped", heading: 10, hasHeading: true, target: 370, tolerance: 1, expected: true},
+		{name: "Items without a heading do not match", target: 0, tolerance: 180},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			item := collectionContents{heading: test.heading, hasHeading: test.hasHeading}
+			assert.Equal(t, test.expected, HeadingWithin(test.target, test.tolerance).match(item))
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistanceFiltered(t *testing.T) {
+	cl := NewCollection()
+	cl.SetWithHeading(0, "north", cell1.lat, cell1.lon, 5)
+	cl.SetWithHeading(1, "south", cell1.lat, cell1.lon, 175)
+	cl.SetWithHeading(2, "also north", cell1.lat, cell1.lon, -10)
+	cl.Set(3, "no heading", cell1.lat, cell1.lon)
+	cl.SetWithHeading(4, "far north", cell2.lat, cell2.lon, 0)
+	// setting without a heading clears it
+	cl.SetWithHeading(5, "cleared", cell1.lat, cell1.lon, 0)
+	cl.Set(5, "cleared", cell1.lat, cell1.lon)
+
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	found, _ := cl.ItemsWithinDistanceFiltered(cell1.lat, cell1.lon, 1000, params, HeadingWithin(350, 20))
+	assert.ElementsMatch(t, []interface{}{"north", "also north"}, found)
+
+	found, _ = cl.ItemsWithinDistanceFiltered(cell1.lat, cell1.lon, 1000, params)
+	assert.ElementsMatch(t, []interface{}{"north", "south", "also north", "no heading", "cleared"}, found)
+
+	// the zero filter matches every item
+	found, _ = cl.ItemsWithinDistanceFiltered(cell1.lat, cell1.lon, 1000, params, SearchFilter{})
+	assert.ElementsMatch(t, []interface{}{"north", "south", "also north", "no heading", "cleared"}, found)
+}
+
+func TestWrapDegrees(t *testing.T) {
+	tests := []struct {
+		name     string
+		degrees  float64
+		expected float64
+	}{
+		{"angles within a turn are unchanged", 90, 90},
+		{"full turns wrap to zero", 360, 0},
+		{"angles past a full turn are wrapped", 370, 10},
+		{"negative angles are wrapped", -10, 350},
+		{"tiny negative angles wrap to zero rather than a full turn", -1e-15, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wrapped := wrapDegrees(test.degrees)
+			assert.InDelta(t, test.expected, wrapped, 1e-9)
+			assert.True(t, wrapped >= 0 && wrapped < 360, "wrapped to %v", wrapped)
+		})
+	}
+}
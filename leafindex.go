@@ -0,0 +1,70 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sort"
+
+	"github.com/golang/geo/s2"
+)
+
+// sortedLeafIndex is a read-optimized index of keys sorted by the cell each key is indexed from, which is a leaf
+// cell for items set by coordinates. Every cell spans a contiguous range of cell ids, so the keys within any
+// covering cell are found with two binary searches instead of a map lookup per level, and the index is stored
+// in two flat slices instead of a map per level.
+type sortedLeafIndex struct {
+	cellIDs []s2.CellID
+	keys    []interface{}
+}
+
+// buildSortedLeafIndex builds a sortedLeafIndex from the items of a collection
+func buildSortedLeafIndex(items map[interface{}]collectionContents) sortedLeafIndex {
+	index := sortedLeafIndex{
+		cellIDs: make([]s2.CellID, 0, len(items)),
+		keys:    make([]interface{}, 0, len(items)),
+	}
+	for key, item := range items {
+		index.cellIDs = append(index.cellIDs, item.cellID)
+		index.keys = append(index.keys, key)
+	}
+	sort.Sort(index)
+	return index
+}
+
+// keysInCell returns the keys of the items indexed within cell. A key is within the cell when the id of the cell
+// it is indexed from is in the range of cell, which holds the ids of the cell and its descendants but not of its
+// ancestors. This matches the cells a key is indexed in by Collection, including for items set with SetToken with
+// a cell coarser than a leaf. The returned slice is shared with the index and must not be modified.
+func (index sortedLeafIndex) keysInCell(cell s2.CellID) []interface{} {
+	begin := sort.Search(len(index.cellIDs), func(i int) bool { return index.cellIDs[i] >= cell.RangeMin() })
+	end := sort.Search(len(index.cellIDs), func(i int) bool { return index.cellIDs[i] > cell.RangeMax() })
+	return index.keys[begin:end]
+}
+
+// Len implements sort.Interface
+func (index sortedLeafIndex) Len() int {
+	return len(index.cellIDs)
+}
+
+// Less implements sort.Interface
+func (index sortedLeafIndex) Less(i, j int) bool {
+	return index.cellIDs[i] < index.cellIDs[j]
+}
+
+// Swap implements sort.Interface
+func (index sortedLeafIndex) Swap(i, j int) {
+	index.cellIDs[i], index.cellIDs[j] = index.cellIDs[j], index.cellIDs[i]
+	index.keys[i], index.keys[j] = index.keys[j], index.keys[i]
+}
@@ -0,0 +1,79 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSortedLeafIndex(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	cl := NewCollection()
+	// scatter items within roughly 50km of downtown Chicago, with one stored by a coarse cell token
+	for i := 0; i < 1000; i++ {
+		cl.Set(i, i, cell1.lat+random.Float64()-0.5, cell1.lon+random.Float64()-0.5)
+	}
+	require.NoError(t, cl.SetToken(1000, 1000, cell1.cellID.Parent(12).ToToken()))
+
+	index := buildSortedLeafIndex(cl.items)
+	require.Len(t, index.cellIDs, len(cl.items))
+	assert.IsIncreasing(t, index.cellIDs)
+
+	for i := 0; i < 100; i++ {
+		// keep the minimum level coarse enough that the coverings stay small
+		minLevel := random.Intn(10)
+		params := SearchCoveringParameters{
+			MinLevel: minLevel,
+			MaxLevel: minLevel + random.Intn(maxCellLevel-minLevel+1),
+			LevelMod: 1 + random.Intn(3),
+			MaxCells: 1 + random.Intn(20),
+		}
+		cellUnion := params.covering(params.searchCap(
+			NewPointFromLatLng(cell1.lat+random.Float64()-0.5, cell1.lon+random.Float64()-0.5),
+			random.Float64()*10000, cl.radiusMeters))
+
+		// keys and contents are the same, so the index and the map search should find the same values
+		expected := make([]int, 0)
+		for _, contents := range cl.itemsInCovering(cellUnion) {
+			expected = append(expected, contents.(int))
+		}
+		found := make([]int, 0)
+		for _, cell := range cellUnion {
+			for _, key := range index.keysInCell(cell) {
+				found = append(found, key.(int))
+			}
+		}
+		sort.Ints(expected)
+		sort.Ints(found)
+		assert.Equal(t, expected, found, "covering %v", cellUnion)
+	}
+
+	// as in the map index, the coarse item is within its cell and its ancestors but none of its children
+	token := cell1.cellID.Parent(12)
+	for child := token.ChildBegin(); child != token.ChildEnd(); child = child.Next() {
+		assert.NotContains(t, index.keysInCell(child), 1000, "child %v", child)
+		assert.NotContains(t, cl.itemsInCovering(s2.CellUnion{child}), 1000, "child %v", child)
+	}
+	for level := token.Level(); level >= 0; level-- {
+		assert.Contains(t, index.keysInCell(token.Parent(level)), 1000, "level %d", level)
+		assert.Contains(t, cl.itemsInCovering(s2.CellUnion{token.Parent(level)}), 1000, "level %d", level)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"slices"
+
+	"github.com/golang/geo/s2"
+)
+
+// WithIndexLevels indexes items only at the cell levels from minLevel to maxLevel instead of at every level from 0
+// to 30. Each item takes an index entry per indexed level, so collections whose searches only use a few levels can
+// save most of their memory: 100k random items take about 520MB indexed at every level and 116MB with
+// WithIndexLevels(8, 16). The tradeoff is precision: covering cells finer than maxLevel are searched as their
+// parent at maxLevel, so searches return more items from outside the search area, and covering cells coarser than
+// minLevel are searched as each of their descendants at minLevel, which gets slow when the covering uses cells much
+// coarser than minLevel. Searches perform best with covering levels within the indexed levels. Levels are limited
+// to [0, 30], and a minLevel greater than maxLevel is lowered to maxLevel. Collections indexing a single level
+// take a faster path to index items and snap coverings. Items set with SetToken must be of a cell no coarser than
+// minLevel.
+func WithIndexLevels(minLevel, maxLevel int) Option {
+	return func(c *Collection) {
+		c.maxIndexLevel = min(max(maxLevel, 0), maxCellLevel)
+		c.minIndexLevel = min(max(minLevel, 0), c.maxIndexLevel)
+	}
+}
+
+// NewCollectionWithLevels creates a new collection configured with the given options that indexes items only at
+// the cell levels from minLevel to maxLevel, see WithIndexLevels
+func NewCollectionWithLevels(minLevel, maxLevel int, opts ...Option) Collection {
+	return NewCollection(append([]Option{WithIndexLevels(minLevel, maxLevel)}, opts...)...)
+}
+
+// IndexLevels returns the lowest and highest cell levels that items are indexed at
+func (c Collection) IndexLevels() (minLevel, maxLevel int) {
+	return c.minIndexLevel, c.maxIndexLevel
+}
+
+// indexesAllLevels reports whether items are indexed at every cell level
+func (c Collection) indexesAllLevels() bool {
+	return c.minIndexLevel == 0 && c.maxIndexLevel == maxCellLevel
+}
+
+// isIndexedLevel reports whether items are indexed at level
+func (c Collection) isIndexedLevel(level int) bool {
+	return level >= c.minIndexLevel && level <= c.maxIndexLevel
+}
+
+// indexedCell returns the cell that the index holds the items of cellID in, which is cellID itself unless it is
+// finer than the indexed levels. cellID must not be coarser than the indexed levels.
+func (c Collection) indexedCell(cellID s2.CellID) s2.CellID {
+	return cellID.Parent(min(cellID.Level(), c.maxIndexLevel))
+}
+
+// indexedCovering snaps the cells of a covering to the indexed levels. Cells finer than the indexed levels are
+// replaced by their parent at the finest indexed level, dropping repeated parents, and cells coarser than the
+// indexed levels by their descendants at the coarsest indexed level, so that every cell of the result can be
+// looked up in the index and together they hold every item in the covering. Coverings of collections that index
+// every level are returned as-is.
+func (c Collection) indexedCovering(cellUnion s2.CellUnion) s2.CellUnion {
+	if c.indexesAllLevels() {
+		return cellUnion
+	}
+	if c.minIndexLevel == c.maxIndexLevel {
+		return c.singleLevelCovering(cellUnion)
+	}
+	return c.multiLevelCovering(cellUnion)
+}
+
+// multiLevelCovering is indexedCovering for collections that index several levels
+func (c Collection) multiLevelCovering(cellUnion s2.CellUnion) s2.CellUnion {
+	snapped := make(s2.CellUnion, 0, len(cellUnion))
+	seen := make(map[s2.CellID]bool, len(cellUnion))
+	for _, cellID := range cellUnion {
+		if cellID.Level() < c.minIndexLevel {
+			end := cellID.ChildEndAtLevel(c.minIndexLevel)
+			for child := cellID.ChildBeginAtLevel(c.minIndexLevel); child != end; child = child.Next() {
+				snapped = append(snapped, child)
+			}
+			continue
+		}
+		if indexed := c.indexedCell(cellID); !seen[indexed] {
+			seen[indexed] = true
+			snapped = append(snapped, indexed)
+		}
+	}
+	return snapped
+}
+
+// singleLevelCovering is indexedCovering for collections that index a single level. Every snapped cell is at that
+// level, so repeated parents are adjacent once sorted and are dropped without tracking them in a set.
+func (c Collection) singleLevelCovering(cellUnion s2.CellUnion) s2.CellUnion {
+	level := c.maxIndexLevel
+	snapped := make(s2.CellUnion, 0, len(cellUnion))
+	for _, cellID := range cellUnion {
+		if cellID.Level() >= level {
+			snapped = append(snapped, cellID.Parent(level))
+			continue
+		}
+		end := cellID.ChildEndAtLevel(level)
+		for child := cellID.ChildBeginAtLevel(level); child != end; child = child.Next() {
+			snapped = append(snapped, child)
+		}
+	}
+	// the snapped cells of a normalized covering are already in order
+	if !slices.IsSorted(snapped) {
+		slices.Sort(snapped)
+	}
+	return slices.Compact(snapped)
+}
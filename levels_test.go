@@ -0,0 +1,181 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithIndexLevels(t *testing.T) {
+	tests := []struct {
+		name                     string
+		minLevel, maxLevel       int
+		expectedMin, expectedMax int
+	}{
+		{"levels within range", 8, 16, 8, 16},
+		{"single level", 12, 12, 12, 12},
+		{"levels past the ends are limited", -5, 40, 0, maxCellLevel},
+		{"min level greater than max level", 20, 10, 10, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			minLevel, maxLevel := NewCollectionWithLevels(test.minLevel, test.maxLevel).IndexLevels()
+			assert.Equal(t, test.expectedMin, minLevel)
+			assert.Equal(t, test.expectedMax, maxLevel)
+		})
+	}
+	minLevel, maxLevel := NewCollection().IndexLevels()
+	assert.Equal(t, 0, minLevel)
+	assert.Equal(t, maxCellLevel, maxLevel)
+}
+
+func TestNewCollectionWithLevels(t *testing.T) {
+	cl := NewCollectionWithLevels(8, 16)
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	require.NoError(t, cl.SetToken(1, "1", cell2.cellID.Parent(12).ToToken()))
+	assert.ErrorIs(t, cl.SetToken(2, "2", cell2.cellID.Parent(4).ToToken()), ErrInvalidCoordinate)
+
+	assert.Len(t, cl.keys[0], 9)
+	assert.Len(t, cl.keys[1], 5)
+	for level := range cl.cells {
+		assert.True(t, level >= 8 && level <= 16, "level %d is indexed", level)
+	}
+	assert.Contains(t, cl.Explain(0), "index is consistent")
+	assert.Contains(t, cl.Explain(1), "index is consistent")
+
+	ok, reason := cl.LevelIsQueryable(20)
+	assert.False(t, ok)
+	assert.Contains(t, reason, "indexed levels 8 to 16")
+	assert.Equal(t, "0", cl.ItemsAtPoint(cell1.lat, cell1.lon)[0].Contents)
+	assert.Equal(t, []interface{}{"0"}, cl.ItemsAtLocation(cell1.lat, cell1.lon))
+	assert.Empty(t, cl.ItemsAtLocation(cell1.lat+1e-5, cell1.lon))
+}
+
+func TestNewCollectionWithLevels_search(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	full := NewCollection()
+	limited := NewCollectionWithLevels(8, 16)
+	// scatter items within roughly 50km of downtown Chicago
+	for i := 0; i < 1000; i++ {
+		lat, lon := cell1.lat+random.Float64()-0.5, cell1.lon+random.Float64()-0.5
+		full.Set(i, i, lat, lon)
+		limited.Set(i, i, lat, lon)
+	}
+
+	tests := []struct {
+		name   string
+		params SearchCoveringParameters
+		// exact is whether the limited collection should find exactly the items the full one finds, rather than
+		// a superset of them
+		exact bool
+	}{
+		{"covering within the indexed levels", SearchCoveringParameters{
+			MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}, true},
+		{"covering coarser than the indexed levels", SearchCoveringParameters{
+			MinLevel: 4, MaxLevel: 6, LevelMod: 1, MaxCells: 8}, true},
+		{"covering finer than the indexed levels", SearchCoveringParameters{
+			MinLevel: 12, MaxLevel: 20, LevelMod: 1, MaxCells: 16}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				lat, lon := cell1.lat+random.Float64()-0.5, cell1.lon+random.Float64()-0.5
+				distance := random.Float64() * 5000
+				expected := full.ItemsWithinDistanceOnly(lat, lon, distance, test.params)
+				found := limited.ItemsWithinDistanceOnly(lat, lon, distance, test.params)
+				if test.exact {
+					assert.ElementsMatch(t, expected, found)
+				} else {
+					assert.Subset(t, found, expected)
+				}
+				assert.ElementsMatch(t,
+					full.ItemsInDistanceRange(lat, lon, 0, distance, test.params),
+					limited.ItemsInDistanceRange(lat, lon, 0, distance, test.params))
+			}
+		})
+	}
+}
+
+func TestCollection_singleLevel(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	single := NewCollectionWithLevels(12, 12)
+	general := NewCollectionWithLevels(12, 12)
+	for i := 0; i < 100; i++ {
+		leaf := s2.CellIDFromLatLng(s2.LatLngFromDegrees(cell1.lat+random.Float64()-0.5, cell1.lon+random.Float64()-0.5))
+		single.indexSingleLevel(i, leaf)
+		general.indexLevels(i, leaf)
+	}
+	// cells coarser than the indexed level are not indexed
+	single.indexSingleLevel(100, cell1.cellID.Parent(4))
+	general.indexLevels(100, cell1.cellID.Parent(4))
+	assert.Equal(t, general.cells, single.cells)
+	assert.Len(t, single.keys, len(general.keys))
+	for key, indices := range general.keys {
+		assert.ElementsMatch(t, indices, single.keys[key], "key %v", key)
+	}
+
+	tests := []struct {
+		name   string
+		params SearchCoveringParameters
+	}{
+		{"covering at the indexed level", SearchCoveringParameters{MinLevel: 12, MaxLevel: 12, LevelMod: 1, MaxCells: 8}},
+		{"covering coarser than the indexed level", SearchCoveringParameters{
+			MinLevel: 8, MaxLevel: 10, LevelMod: 1, MaxCells: 8}},
+		{"covering finer than the indexed level", SearchCoveringParameters{
+			MinLevel: 12, MaxLevel: 20, LevelMod: 1, MaxCells: 16}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cellUnion := test.params.covering(test.params.searchCap(s2.PointFromLatLng(s2.LatLngFromDegrees(cell1.lat, cell1.lon)), 5000, single.radiusMeters))
+			assert.ElementsMatch(t, single.multiLevelCovering(cellUnion), single.singleLevelCovering(cellUnion))
+		})
+	}
+}
+
+func BenchmarkCollection_singleLevel(b *testing.B) {
+	batch := benchmarkLoad(1000)
+	leaves := make([]s2.CellID, 0, len(batch))
+	for _, item := range batch {
+		leaves = append(leaves, s2.CellIDFromLatLng(s2.LatLngFromDegrees(item.Latitude, item.Longitude)))
+	}
+	// a covering finer than the indexed level, so that most of its cells snap to a repeated parent
+	params := SearchCoveringParameters{MinLevel: 16, MaxLevel: 24, LevelMod: 1, MaxCells: 64}
+	cellUnion := params.covering(params.searchCap(s2.PointFromLatLng(s2.LatLngFromDegrees(cell1.lat, cell1.lon)), 1000, EarthRadiusMeters))
+	cl := NewCollectionWithLevels(16, 16)
+	for _, bench := range []struct {
+		name     string
+		index    func(key interface{}, leaf s2.CellID)
+		covering func(cellUnion s2.CellUnion) s2.CellUnion
+	}{
+		{"general", cl.indexLevels, cl.multiLevelCovering},
+		{"single level", cl.indexSingleLevel, cl.singleLevelCovering},
+	} {
+		b.Run(bench.name+"/index", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bench.index(i%len(leaves), leaves[i%len(leaves)])
+			}
+		})
+		b.Run(bench.name+"/covering", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				bench.covering(cellUnion)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewUnsafeCollection(t *testing.T) {
+	cl := NewUnsafeCollection(WithIndexLevels(8, 16))
+	assert.Equal(t, noLocker{}, cl.mutex)
+	minLevel, maxLevel := cl.IndexLevels()
+	assert.Equal(t, 8, minLevel)
+	assert.Equal(t, 16, maxLevel)
+
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	assert.Equal(t, []interface{}{"0"}, cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000, params))
+
+	clone := cl.Clone()
+	assert.Equal(t, noLocker{}, clone.mutex)
+	clone.Delete(0)
+	assert.Equal(t, "0", cl.ItemByKey(0))
+
+	// unsafe collections are still told apart when merging
+	other := NewUnsafeCollection()
+	other.Set(2, "2", cell1.lat, cell1.lon)
+	cl.Merge(other)
+	assert.ElementsMatch(t, []interface{}{"0", "2"}, cl.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000, params))
+	cl.Merge(cl)
+	assert.Equal(t, 3, cl.Count())
+}
+
+func BenchmarkCollection_Set_withoutLocking(b *testing.B) {
+	// index a single level and keep setting items in place, so that the cost of a Set is mostly that of locking
+	batch := benchmarkLoad(1000)
+	for _, bench := range []struct {
+		name string
+		opts []Option
+	}{
+		{"locked", []Option{WithIndexLevels(16, 16)}},
+		{"unlocked", []Option{WithIndexLevels(16, 16), WithoutLocking()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			cl := NewCollection(bench.opts...)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				item := batch[i%len(batch)]
+				cl.Set(item.Key, item.Contents, item.Latitude, item.Longitude)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"github.com/samber/lo"
+)
+
+// MultiCollection implements the LocationCollection interface over several underlying collections (shards) so
+// that they can be searched as one. Writes are routed to a single shard while reads fan out to all of them and
+// merge the results.
+type MultiCollection struct {
+	shardForKey func(key interface{}) int
+	shards      []LocationCollection
+}
+
+var (
+	_ Reader             = MultiCollection{}
+	_ Writer             = MultiCollection{}
+	_ LocationCollection = MultiCollection{}
+)
+
+// NewMultiCollection creates a MultiCollection over the given shards. shardForKey returns the index of the
+// shard that items with a given key are stored in and must be in the range [0, len(shards)).
+func NewMultiCollection(shardForKey func(key interface{}) int, shards ...LocationCollection) MultiCollection {
+	return MultiCollection{shardForKey: shardForKey, shards: shards}
+}
+
+// Set adds an item to the shard selected for its key.
+func (m MultiCollection) Set(key, contents interface{}, latitude, longitude float64) {
+	m.shards[m.shardForKey(key)].Set(key, contents, latitude, longitude)
+}
+
+// Delete removes an item by its key from every shard.
+func (m MultiCollection) Delete(key interface{}) {
+	for _, shard := range m.shards {
+		shard.Delete(key)
+	}
+}
+
+// ItemsWithinDistance searches every shard and returns the combined results. Since every shard is searched
+// with the same parameters, the covering of the first shard is returned.
+func (m MultiCollection) ItemsWithinDistance(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) ([]interface{}, SearchCoveringResult) {
+	foundItems := make([]interface{}, 0)
+	var covering SearchCoveringResult
+	for i, shard := range m.shards {
+		items, shardCovering := shard.ItemsWithinDistance(latitude, longitude, distanceMeters, params)
+		foundItems = append(foundItems, items...)
+		if i == 0 {
+			covering = shardCovering
+		}
+	}
+	return foundItems, covering
+}
+
+// ItemsWithinDistanceOnly searches every shard and returns the combined items without the covering.
+func (m MultiCollection) ItemsWithinDistanceOnly(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) []interface{} {
+	foundItems := make([]interface{}, 0)
+	for _, shard := range m.shards {
+		foundItems = append(foundItems, shard.ItemsWithinDistanceOnly(latitude, longitude, distanceMeters, params)...)
+	}
+	return foundItems
+}
+
+// ItemByKey returns the contents stored by key in the first shard that has it
+func (m MultiCollection) ItemByKey(key interface{}) interface{} {
+	for _, shard := range m.shards {
+		if contents := shard.ItemByKey(key); contents != nil {
+			return contents
+		}
+	}
+	return nil
+}
+
+// GetItems gets the items from all shards based on arg pageSize, startIndex. Items are ordered by shard.
+func (m MultiCollection) GetItems(pageSize, startIndex int) []interface{} {
+	items := make([]interface{}, 0)
+	for _, shard := range m.shards {
+		if len(items) >= startIndex+pageSize {
+			break
+		}
+		items = append(items, shard.GetItems(startIndex+pageSize-len(items), 0)...)
+	}
+	return lo.Slice(items, startIndex, startIndex+pageSize)
+}
+
+// NearestLocator is implemented by collections that can find the items nearest to a point along with their keys
+// and distances, which MultiCollection needs to merge the nearest items of its shards. KNearestLocated must return
+// at most k items ordered by distance and then by key.
+type NearestLocator interface {
+	KNearestLocated(latitude, longitude float64, k int, params SearchCoveringParameters) []LocatedItem
+}
+
+var (
+	_ NearestLocator = Collection{}
+	_ NearestLocator = MultiCollection{}
+)
+
+// KNearestNeighbors returns the contents of the k items nearest to the given latitude and longitude across every
+// shard, ordered by their great-circle distance and then by key, as Collection.KNearestNeighbors would for a
+// single collection holding the items of every shard. The k nearest items of each shard are found and merged.
+// Only shards that implement NearestLocator, as Collections and MultiCollections do, can be searched this way;
+// other shards are skipped.
+func (m MultiCollection) KNearestNeighbors(
+	latitude, longitude float64, k int, params SearchCoveringParameters,
+) []interface{} {
+	found := m.KNearestLocated(latitude, longitude, k, params)
+	contents := make([]interface{}, 0, len(found))
+	for _, item := range found {
+		contents = append(contents, item.Contents)
+	}
+	return contents
+}
+
+// KNearestLocated returns the k items nearest to the given latitude and longitude across every shard along with
+// their keys, coordinates and distances, skipping shards that do not implement NearestLocator
+func (m MultiCollection) KNearestLocated(
+	latitude, longitude float64, k int, params SearchCoveringParameters,
+) []LocatedItem {
+	found := make([]LocatedItem, 0)
+	if k <= 0 {
+		return found
+	}
+	for _, shard := range m.shards {
+		if locator, ok := shard.(NearestLocator); ok {
+			found = append(found, locator.KNearestLocated(latitude, longitude, k, params)...)
+		}
+	}
+	sortLocatedItems(found)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
@@ -0,0 +1,110 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiCollection(t *testing.T) {
+	shards := []Collection{NewCollection(), NewCollection(), NewCollection()}
+	mc := NewMultiCollection(
+		func(key interface{}) int { return key.(int) % len(shards) },
+		shards[0], shards[1], shards[2],
+	)
+	// three items near Chicago, one in each shard, and one in Manhattan
+	mc.Set(0, "0", cell1.lat, cell1.lon)
+	mc.Set(1, "1", cell1.lat+0.001, cell1.lon)
+	mc.Set(2, "2", cell1.lat, cell1.lon+0.001)
+	mc.Set(3, "3", cell2.lat, cell2.lon)
+	assert.Len(t, shards[0].items, 2)
+	assert.Len(t, shards[1].items, 1)
+	assert.Len(t, shards[2].items, 1)
+
+	params := SearchCoveringParameters{MaxLevel: 5, MinLevel: 5, LevelMod: 1, MaxCells: 5}
+	results, covering := mc.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.ElementsMatch(t, []interface{}{"0", "1", "2"}, results)
+	assert.NotEmpty(t, covering)
+	assert.ElementsMatch(t, results, mc.ItemsWithinDistanceOnly(cell1.lat, cell1.lon, 1000, params))
+
+	assert.Equal(t, "2", mc.ItemByKey(2))
+	assert.Nil(t, mc.ItemByKey(4))
+	assert.Len(t, mc.GetItems(10, 0), 4)
+	assert.Len(t, mc.GetItems(2, 1), 2)
+	assert.Len(t, mc.GetItems(10, 3), 1)
+	assert.Empty(t, mc.GetItems(10, 4))
+
+	mc.Delete(1)
+	assert.Nil(t, mc.ItemByKey(1))
+	results, _ = mc.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.ElementsMatch(t, []interface{}{"0", "2"}, results)
+}
+
+func TestMultiCollection_KNearestNeighbors(t *testing.T) {
+	single, items := randomCollection(200)
+	shards := []Collection{NewCollection(), NewCollection(), NewCollection()}
+	mc := NewMultiCollection(
+		func(key interface{}) int { return key.(int) % len(shards) },
+		shards[0], shards[1], shards[2],
+	)
+	for _, item := range items {
+		mc.Set(item.Key, item.Contents, item.Latitude, item.Longitude)
+	}
+	tests := []struct {
+		name string
+		k    int
+	}{
+		{"Fewer items than a shard holds", 5},
+		{"More items than a shard holds", 100},
+		{"More items than every shard holds", 300},
+		{"No items", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t,
+				single.KNearestNeighbors(cell1.lat, cell1.lon, test.k, nearestParams),
+				mc.KNearestNeighbors(cell1.lat, cell1.lon, test.k, nearestParams))
+		})
+	}
+}
+
+// locatingShard is a custom shard that finds its nearest items through the Collection it wraps
+type locatingShard struct {
+	LocationCollection
+	collection Collection
+}
+
+// KNearestLocated implements NearestLocator
+func (s locatingShard) KNearestLocated(
+	latitude, longitude float64, k int, params SearchCoveringParameters,
+) []LocatedItem {
+	return s.collection.KNearestLocated(latitude, longitude, k, params)
+}
+
+func TestMultiCollection_KNearestNeighbors_customShards(t *testing.T) {
+	located := NewCollection()
+	located.Set(0, "0", cell1.lat, cell1.lon)
+	// shards that only implement LocationCollection can't be searched for their nearest items
+	unlocated := NewCollection()
+	unlocated.Set(1, "1", cell1.lat, cell1.lon)
+	mc := NewMultiCollection(
+		func(key interface{}) int { return key.(int) % 2 },
+		locatingShard{LocationCollection: located, collection: located},
+		struct{ LocationCollection }{unlocated},
+	)
+	assert.Equal(t, []interface{}{"0"}, mc.KNearestNeighbors(cell1.lat, cell1.lon, 2, nearestParams))
+}
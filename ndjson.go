@@ -0,0 +1,94 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ndjsonVersion is the version of the newline-delimited JSON format written by WriteNDJSON. Every record carries
+// the version it was written with in its "v" field. Version 1 records, which predate the field, have only the key,
+// contents and coordinates, and version 2 adds the optional heading. New versions only ever add fields, so readers
+// decode records of any version by reading the fields they know about and ignoring the rest: records of versions
+// older than the reader's are missing the newer fields, which are left unset, and records of newer versions load
+// without the fields the reader does not know about.
+const ndjsonVersion = 2
+
+// ndjsonRecord is a single item in the newline-delimited JSON format
+type ndjsonRecord struct {
+	Key       interface{} `json:"key"`
+	Contents  interface{} `json:"contents"`
+	Heading   *float64    `json:"heading,omitempty"`
+	Version   int         `json:"v,omitempty"`
+	Latitude  float64     `json:"lat"`
+	Longitude float64     `json:"lon"`
+}
+
+// WriteNDJSON writes every item in the collection to w as newline-delimited JSON, one
+// {"v", "key", "contents", "lat", "lon", "heading"} object per line, where the heading is only present for items
+// that have one. See ndjsonVersion for how the format is versioned. Each line is written to w as soon as it is encoded, so the
+// export never holds more than one item in memory regardless of the size of the collection. The read lock is held
+// for the duration of the export so that it is a consistent snapshot, which blocks writers until it is done.
+// Keys and contents must be serializable with encoding/json.
+func (c Collection) WriteNDJSON(w io.Writer) error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	encoder := json.NewEncoder(w)
+	now := c.now()
+	for key, item := range c.items {
+		if item.expired(now) {
+			continue
+		}
+		record := ndjsonRecord{
+			Key:       key,
+			Contents:  item.contents,
+			Version:   ndjsonVersion,
+			Latitude:  item.latitude,
+			Longitude: item.longitude,
+		}
+		if item.hasHeading {
+			record.Heading = &item.heading
+		}
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to write item with key %v: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ReadNDJSON creates a new collection, configured with the given options, from newline-delimited JSON in the
+// format written by WriteNDJSON, of any version. Keys and contents are decoded into the generic types of
+// encoding/json, so numbers are read back as float64 and objects as map[string]interface{}.
+func ReadNDJSON(r io.Reader, opts ...Option) (Collection, error) {
+	c := NewCollection(opts...)
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var record ndjsonRecord
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return c, nil
+			}
+			return Collection{}, fmt.Errorf("failed to read record %d: %w", line, err)
+		}
+		if record.Heading != nil {
+			c.SetWithHeading(record.Key, record.Contents, record.Latitude, record.Longitude, *record.Heading)
+			continue
+		}
+		c.Set(record.Key, record.Contents, record.Latitude, record.Longitude)
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingWriter records every write made to it
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestCollection_NDJSON(t *testing.T) {
+	cl := NewCollection()
+	cl.Set("a", map[string]interface{}{"name": "a"}, cell1.lat, cell1.lon)
+	cl.Set("b", "b", cell2.lat, cell2.lon)
+	cl.Set("c", 3.5, cell2.lat+0.01, cell2.lon)
+	cl.SetWithHeading("d", "d", cell1.lat, cell1.lon, 90)
+
+	var w countingWriter
+	require.NoError(t, cl.WriteNDJSON(&w))
+	// each record is written to the writer on its own rather than buffered into a single write
+	assert.Equal(t, 4, w.writes)
+	assert.Equal(t, 4, strings.Count(w.String(), "\n"))
+
+	decoded, err := ReadNDJSON(&w)
+	require.NoError(t, err)
+	assert.Equal(t, cl.items, decoded.items)
+	assert.Equal(t, cl.keys, decoded.keys)
+}
+
+func TestReadNDJSON_error(t *testing.T) {
+	_, err := ReadNDJSON(strings.NewReader(`{"key": "a", "contents": "a", "lat": 1, "lon": 2}` + "\n{"))
+	assert.ErrorContains(t, err, "record 2")
+}
+
+func TestReadNDJSON_versions(t *testing.T) {
+	tests := []struct {
+		name            string
+		record          string
+		expectedHeading bool
+	}{
+		{
+			name:   "Version 1 records without a version field are read",
+			record: `{"key": "a", "contents": "a", "lat": 41.8, "lon": -87.6}`,
+		}, {
+			name:            "Version 2 records are read with their heading",
+			record:          `{"v": 2, "key": "a", "contents": "a", "lat": 41.8, "lon": -87.6, "heading": 90}`,
+			expectedHeading: true,
+		}, {
+			name:            "Records of newer versions are read without their unknown fields",
+			record:          `{"v": 3, "key": "a", "contents": "a", "lat": 41.8, "lon": -87.6, "heading": 90, "tags": ["x"]}`,
+			expectedHeading: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := ReadNDJSON(strings.NewReader(test.record + "\n"))
+			require.NoError(t, err)
+			require.Contains(t, decoded.items, "a")
+			item := decoded.items["a"]
+			assert.Equal(t, "a", item.contents)
+			assert.Equal(t, 41.8, item.latitude)
+			assert.Equal(t, -87.6, item.longitude)
+			assert.Equal(t, test.expectedHeading, item.hasHeading)
+		})
+	}
+}
+
+func TestWriteNDJSON_readByVersion1(t *testing.T) {
+	// version1Record is the record as readers of the first version of the format decode it
+	type version1Record struct {
+		Key       interface{} `json:"key"`
+		Contents  interface{} `json:"contents"`
+		Latitude  float64     `json:"lat"`
+		Longitude float64     `json:"lon"`
+	}
+	cl := NewCollection()
+	cl.SetWithHeading("a", "a", cell1.lat, cell1.lon, 90)
+	var w bytes.Buffer
+	require.NoError(t, cl.WriteNDJSON(&w))
+	assert.Contains(t, w.String(), `"v":2`)
+
+	var record version1Record
+	require.NoError(t, json.NewDecoder(&w).Decode(&record))
+	assert.Equal(t, version1Record{Key: "a", Contents: "a", Latitude: cell1.lat, Longitude: cell1.lon}, record)
+}
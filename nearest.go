@@ -0,0 +1,371 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/golang/geo/s2"
+)
+
+// LocatedItem is an item found by a search along with its key, stored coordinates and distance from the point
+// that was searched.
+type LocatedItem struct {
+	Key            interface{}
+	Contents       interface{}
+	Latitude       float64
+	Longitude      float64
+	DistanceMeters float64
+}
+
+// Cursor records how far a nearest-first search has progressed: the distance and key of the last item returned.
+// The zero Cursor starts a search from the beginning. Since a cursor is only a position, it stays valid while the
+// collection changes. A page continues with the items that are farther than the last item returned, or as far and
+// ordered after its key, as of the time the page is requested. Items inserted or moved closer than the cursor
+// after it was returned are not returned by later pages, and an item that moves past the cursor may be returned
+// again.
+type Cursor struct {
+	distanceMeters float64
+	key            interface{}
+	started        bool
+	done           bool
+}
+
+// Done reports whether the page that returned the cursor was the last one, i.e. it returned fewer items than
+// requested.
+func (cur Cursor) Done() bool {
+	return cur.done
+}
+
+// before reports whether an item at distanceMeters with key comes before or at the cursor's position
+func (cur Cursor) before(distanceMeters float64, key interface{}) bool {
+	if !cur.started {
+		return false
+	}
+	if distanceMeters != cur.distanceMeters {
+		return distanceMeters < cur.distanceMeters
+	}
+	return compareKeys(key, cur.key) <= 0
+}
+
+// nearestInitialRadiusMeters is the radius of the first cap searched by a nearest-first search when the cursor
+// is at the start
+const nearestInitialRadiusMeters = 1000
+
+// ringOverlapMeters shrinks the area already searched by a nearest-first search before it is excluded from the
+// next, larger cap, so that rounding error cannot exclude items just past the edge of the smaller cap
+const ringOverlapMeters = 1
+
+// nearestParams are the covering parameters used by nearest-first searches
+var nearestParams = SearchCoveringParameters{MinLevel: 0, MaxLevel: maxCellLevel, LevelMod: 1, MaxCells: 8}
+
+// KNearestPage returns up to pageSize items in order of increasing distance from the given latitude and
+// longitude, starting after the cursor, along with the cursor to pass to get the next page. Items at the same
+// distance are ordered by key. Each page only searches outward from the previous page's cursor, so deep pages
+// cost no more than the first one. See Cursor for how paging behaves while the collection changes.
+func (c Collection) KNearestPage(latitude, longitude float64, pageSize int, cursor Cursor) ([]LocatedItem, Cursor) {
+	if pageSize <= 0 || cursor.done {
+		return []LocatedItem{}, cursor
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	found := c.nearest(NewPointFromLatLng(latitude, longitude), pageSize, cursor, nearestParams, nil)
+	next := Cursor{started: true, done: len(found) < pageSize}
+	if len(found) == 0 {
+		next.distanceMeters, next.key = cursor.distanceMeters, cursor.key
+	} else {
+		last := found[len(found)-1]
+		next.distanceMeters, next.key = last.DistanceMeters, last.Key
+	}
+	return found, next
+}
+
+// KNearestNeighbors returns the contents of the k items nearest to the given latitude and longitude, ordered by
+// their great-circle distance and then by key. The params control the coverings of the caps the search expands
+// through until it has found k items, up to the whole sphere, so fewer than k items are only returned when the
+// collection holds fewer than k. Once a cap is wider than the cells of MinLevel, it is covered with larger cells
+// to keep its covering small.
+func (c Collection) KNearestNeighbors(latitude, longitude float64, k int, params SearchCoveringParameters) []interface{} {
+	found := c.KNearestLocated(latitude, longitude, k, params)
+	contents := make([]interface{}, 0, len(found))
+	for _, item := range found {
+		contents = append(contents, item.Contents)
+	}
+	return contents
+}
+
+// KNearestLocated returns the k items nearest to the given latitude and longitude in the same way as
+// KNearestNeighbors, along with their keys, coordinates and distances
+func (c Collection) KNearestLocated(
+	latitude, longitude float64, k int, params SearchCoveringParameters,
+) []LocatedItem {
+	if k <= 0 {
+		return []LocatedItem{}
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.nearest(NewPointFromLatLng(latitude, longitude), k, Cursor{}, params, nil)
+}
+
+// Nearest returns the key, contents and great-circle distance of the item closest to the given latitude and
+// longitude, with ties going to the lowest key, or ok false if the collection is empty. The params control the
+// coverings of the search as in KNearestNeighbors.
+func (c Collection) Nearest(
+	latitude, longitude float64, params SearchCoveringParameters,
+) (key, contents interface{}, distanceMeters float64, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	found := c.nearest(NewPointFromLatLng(latitude, longitude), 1, Cursor{}, params, nil)
+	if len(found) == 0 {
+		return nil, nil, 0, false
+	}
+	return found[0].Key, found[0].Contents, found[0].DistanceMeters, true
+}
+
+// NearestMatching returns the contents and great-circle distance of the item closest to the given latitude and
+// longitude whose contents match reports true for, with ties going to the lowest key. The search expands through
+// the same caps as Nearest, past any items that do not match, until it finds a match or has searched the whole
+// sphere, in which case ok is false. match is called with the contents of each item the search passes, as copied
+// by the contents copier if the collection has one, while the read lock is held, so it must not use the
+// collection.
+func (c Collection) NearestMatching(
+	latitude, longitude float64, params SearchCoveringParameters, match func(contents interface{}) bool,
+) (contents interface{}, distanceMeters float64, ok bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	found := c.nearest(NewPointFromLatLng(latitude, longitude), 1, Cursor{}, params,
+		func(_ interface{}, item collectionContents) bool { return match(c.copied(item.contents)) })
+	if len(found) == 0 {
+		return nil, 0, false
+	}
+	return found[0].Contents, found[0].DistanceMeters, true
+}
+
+// KNearestExcluding returns the k items nearest to the given latitude and longitude whose keys are not in exclude,
+// ordered by distance and then key. The search keeps expanding past excluded items, so k items are returned as
+// long as the collection holds that many that are not excluded.
+func (c Collection) KNearestExcluding(latitude, longitude float64, k int, exclude map[interface{}]bool) []LocatedItem {
+	if k <= 0 {
+		return []LocatedItem{}
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.nearest(NewPointFromLatLng(latitude, longitude), k, Cursor{}, nearestParams,
+		func(key interface{}, _ collectionContents) bool { return !exclude[key] })
+}
+
+// WithinDistanceBanded searches outward from the given latitude and longitude in bands bandMeters wide, up to
+// maxMeters, calling fn once per band with the band's index and the items in it, ordered by distance and then
+// key. Band i holds the items further than i*bandMeters and up to (i+1)*bandMeters away, with items at the center
+// in band 0 and the last band ending at maxMeters, so every item within maxMeters is passed to fn exactly once
+// while the collection is unchanged. fn is called for every band, including bands without items, until it
+// returns false. Each band only searches the ring outside of the band before it, and the read lock is released
+// while fn runs so that fn may use the collection; items that move while the search runs may be missed or passed
+// in more than one band. A maxMeters past half of the circumference of the sphere, including +Inf, searches the
+// whole sphere. Invalid coordinates, a negative maxMeters or a bandMeters that is not positive and finite call fn
+// for no bands and return an error wrapping ErrInvalidCoordinate or ErrInvalidDistance.
+func (c Collection) WithinDistanceBanded(
+	latitude, longitude, maxMeters, bandMeters float64,
+	fn func(band int, items []LocatedItem) bool,
+	params SearchCoveringParameters,
+) error {
+	if err := validateSearch(latitude, longitude, maxMeters); err != nil {
+		return err
+	}
+	if !(bandMeters > 0) || math.IsInf(bandMeters, 1) {
+		return fmt.Errorf("%w: band width %v is not positive and finite", ErrInvalidDistance, bandMeters)
+	}
+	// no point of the sphere is further than half of its circumference, which also bounds the bands of +Inf
+	maxMeters = math.Min(maxMeters, math.Pi*c.radiusMeters)
+	center := NewPointFromLatLng(latitude, longitude)
+	for band, innerRadius := 0, 0.0; innerRadius < maxMeters || band == 0; band++ {
+		radius := math.Min(float64(band+1)*bandMeters, maxMeters)
+		if !fn(band, c.itemsInBand(center, innerRadius, radius, params)) {
+			return nil
+		}
+		innerRadius = radius
+	}
+	return nil
+}
+
+// itemsInBand returns the items further than innerRadius and up to radius meters away from center, or also
+// those at the center when innerRadius is zero, ordered by distance and then key
+func (c Collection) itemsInBand(
+	center s2.Point, innerRadius, radius float64, params SearchCoveringParameters,
+) []LocatedItem {
+	cellUnion := params.covering(params.searchCap(center, radius, c.radiusMeters))
+	cellUnion.Normalize()
+	if innerRadius > ringOverlapMeters {
+		searchedCap := capFromCenterMeters(center, innerRadius-ringOverlapMeters, c.radiusMeters)
+		searched := params.regionCoverer(searchedCap).InteriorCovering(searchedCap)
+		cellUnion = s2.CellUnionFromDifference(cellUnion, searched)
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	found := make([]LocatedItem, 0)
+	c.eachInCovering(cellUnion, func(key interface{}, item collectionContents) bool {
+		distance := c.distance(center, item.point)
+		if distance > radius || (innerRadius > 0 && distance <= innerRadius) {
+			return true
+		}
+		found = append(found, c.locatedItem(key, item, distance))
+		return true
+	})
+	sortLocatedItems(found)
+	return found
+}
+
+// nearest returns up to k items after the cursor in order of their distance from center and then their key. When
+// match is given, only items for which it returns true are considered. The search covers a cap that grows until
+// it holds k items or the whole sphere, with each cap only searching the ring outside of the one before it. The
+// caller must hold the read lock.
+func (c Collection) nearest(
+	center s2.Point, k int, after Cursor, params SearchCoveringParameters,
+	match func(key interface{}, item collectionContents) bool,
+) []LocatedItem {
+	found := make([]LocatedItem, 0, k)
+	maxRadius := math.Pi * c.radiusMeters
+	innerRadius := after.distanceMeters
+	radius := math.Max(2*innerRadius, nearestInitialRadiusMeters)
+	for first := true; ; first = false {
+		radius = math.Min(radius, maxRadius)
+		ringParams := params
+		// cells of MinLevel could take millions to cover a large cap, so use cells as wide as the cap instead
+		ringParams.MinLevel = min(params.MinLevel, s2.MinWidthMetric.MaxLevel(radius/c.radiusMeters))
+		ringParams.MaxLevel = max(params.MaxLevel, ringParams.MinLevel)
+		cellUnion := ringParams.covering(capFromCenterMeters(center, radius, c.radiusMeters))
+		cellUnion.Normalize()
+		if innerRadius > ringOverlapMeters {
+			searchedCap := capFromCenterMeters(center, innerRadius-ringOverlapMeters, c.radiusMeters)
+			searched := ringParams.regionCoverer(searchedCap).InteriorCovering(searchedCap)
+			cellUnion = s2.CellUnionFromDifference(cellUnion, searched)
+		}
+		c.eachInCovering(cellUnion, func(key interface{}, item collectionContents) bool {
+			distance := c.distance(center, item.point)
+			if distance > radius || (!first && distance <= innerRadius) || after.before(distance, key) {
+				return true
+			}
+			if match != nil && !match(key, item) {
+				return true
+			}
+			found = append(found, c.locatedItem(key, item, distance))
+			return true
+		})
+		if len(found) >= k || radius >= maxRadius {
+			break
+		}
+		innerRadius, radius = radius, 2*radius
+	}
+	sortLocatedItems(found)
+	if len(found) > k {
+		found = found[:k]
+	}
+	return found
+}
+
+// ItemsWithinDistanceOfAny returns the items within distanceMeters of any of the centers, given as latitude and
+// longitude pairs, with each item returned once along with its distance from the closest center. The coverings
+// of the centers are merged before searching, so items in overlapping areas are only visited once. Like
+// ItemsWithinDistance, items in covering cells that extend past distanceMeters may be returned. Results are
+// ordered by distance and then key. No items are returned if any center is an invalid coordinate or the distance
+// is negative.
+func (c Collection) ItemsWithinDistanceOfAny(
+	centers [][2]float64, distanceMeters float64, params SearchCoveringParameters,
+) []LocatedItem {
+	points := make([]s2.Point, 0, len(centers))
+	coverings := make([]s2.CellUnion, 0, len(centers))
+	for _, center := range centers {
+		if validateSearch(center[0], center[1], distanceMeters) != nil {
+			return []LocatedItem{}
+		}
+		point := NewPointFromLatLng(center[0], center[1])
+		points = append(points, point)
+		coverings = append(coverings, params.covering(params.searchCap(point, distanceMeters, c.radiusMeters)))
+	}
+	cellUnion := s2.CellUnionFromUnion(coverings...)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	found := make([]LocatedItem, 0)
+	c.eachInCovering(cellUnion, func(key interface{}, item collectionContents) bool {
+		position := item.point
+		distance := math.Inf(1)
+		for _, point := range points {
+			distance = math.Min(distance, c.distance(point, position))
+		}
+		found = append(found, c.locatedItem(key, item, distance))
+		return true
+	})
+	sortLocatedItems(found)
+	return found
+}
+
+// locatedItem builds the LocatedItem for a stored item found distanceMeters from a search center
+func (c Collection) locatedItem(key interface{}, item collectionContents, distanceMeters float64) LocatedItem {
+	return LocatedItem{
+		Key:            key,
+		Contents:       c.copied(item.contents),
+		Latitude:       item.latitude,
+		Longitude:      item.longitude,
+		DistanceMeters: distanceMeters,
+	}
+}
+
+// sortLocatedItems orders items by increasing distance, breaking ties by key
+func sortLocatedItems(items []LocatedItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].DistanceMeters != items[j].DistanceMeters {
+			return items[i].DistanceMeters < items[j].DistanceMeters
+		}
+		return compareKeys(items[i].Key, items[j].Key) < 0
+	})
+}
+
+// compareKeys orders keys of any type so that results with equal distances come back in a stable order. Keys of
+// the same integer, float or string type compare by value. Any other keys compare by their type name and then
+// their formatted value, so distinct keys that format the same compare as equal.
+func compareKeys(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		if other, ok := b.(int); ok {
+			return cmp.Compare(a, other)
+		}
+	case int64:
+		if other, ok := b.(int64); ok {
+			return cmp.Compare(a, other)
+		}
+	case uint64:
+		if other, ok := b.(uint64); ok {
+			return cmp.Compare(a, other)
+		}
+	case float64:
+		if other, ok := b.(float64); ok {
+			return cmp.Compare(a, other)
+		}
+	case string:
+		if other, ok := b.(string); ok {
+			return strings.Compare(a, other)
+		}
+	}
+	if typeA, typeB := fmt.Sprintf("%T", a), fmt.Sprintf("%T", b); typeA != typeB {
+		return strings.Compare(typeA, typeB)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
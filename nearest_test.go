@@ -0,0 +1,354 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// randomCollection returns a collection of n items scattered within roughly 50km of downtown Chicago, keyed and
+// with contents of their index, along with the items as LocatedItems measured from cell1
+func randomCollection(n int) (Collection, []LocatedItem) {
+	random := rand.New(rand.NewSource(1))
+	cl := NewCollection()
+	center := NewPointFromLatLng(cell1.lat, cell1.lon)
+	items := make([]LocatedItem, 0, n)
+	for i := 0; i < n; i++ {
+		lat, lon := cell1.lat+random.Float64()-0.5, cell1.lon+random.Float64()-0.5
+		if i%10 == 0 && i > 0 {
+			// share some coordinates so that distances tie
+			lat, lon = items[i-1].Latitude, items[i-1].Longitude
+		}
+		cl.Set(i, i, lat, lon)
+		items = append(items, LocatedItem{
+			Key: i, Contents: i, Latitude: lat, Longitude: lon,
+			DistanceMeters: EarthDistanceMeters(center, NewPointFromLatLng(lat, lon)),
+		})
+	}
+	sortLocatedItems(items)
+	return cl, items
+}
+
+func TestCollection_KNearestPage(t *testing.T) {
+	cl, expected := randomCollection(500)
+	// one item far away must still be found once the nearby ones run out
+	cl.Set(500, 500, cell2.lat, cell2.lon)
+	expected = append(expected, LocatedItem{
+		Key: 500, Contents: 500, Latitude: cell2.lat, Longitude: cell2.lon,
+		DistanceMeters: EarthDistanceMeters(NewPointFromLatLng(cell1.lat, cell1.lon), NewPointFromLatLng(cell2.lat, cell2.lon)),
+	})
+
+	found := make([]LocatedItem, 0, len(expected))
+	cursor := Cursor{}
+	for pages := 0; !cursor.Done(); pages++ {
+		require.Less(t, pages, len(expected), "paging did not finish")
+		var page []LocatedItem
+		page, cursor = cl.KNearestPage(cell1.lat, cell1.lon, 7, cursor)
+		require.LessOrEqual(t, len(page), 7)
+		found = append(found, page...)
+	}
+	assert.Equal(t, expected, found)
+
+	page, next := cl.KNearestPage(cell1.lat, cell1.lon, 7, cursor)
+	assert.Empty(t, page)
+	assert.True(t, next.Done())
+}
+
+func BenchmarkCollection_KNearestNeighbors(b *testing.B) {
+	cl, _ := randomCollection(20000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cl.KNearestNeighbors(cell1.lat, cell1.lon, 1000, nearestParams)
+	}
+}
+
+func TestCollection_KNearestPage_empty(t *testing.T) {
+	cl := NewCollection()
+	page, cursor := cl.KNearestPage(cell1.lat, cell1.lon, 10, Cursor{})
+	assert.Empty(t, page)
+	assert.True(t, cursor.Done())
+
+	cl.Set(0, 0, cell1.lat, cell1.lon)
+	page, cursor = cl.KNearestPage(cell1.lat, cell1.lon, 0, Cursor{})
+	assert.Empty(t, page)
+	assert.False(t, cursor.Done())
+}
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     interface{}
+		expected int
+	}{
+		{name: "Integers compare by value", a: 2, b: 10, expected: -1},
+		{name: "Strings compare by value", a: "b", b: "a", expected: 1},
+		{name: "Equal keys compare equal", a: "a", b: "a", expected: 0},
+		{name: "Keys of different types compare by type", a: "1", b: 1, expected: 1},
+		{name: "Other keys compare by formatted value", a: struct{ n int }{2}, b: struct{ n int }{10}, expected: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, compareKeys(test.a, test.b))
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistanceOfAny(t *testing.T) {
+	cl := NewCollection()
+	// three items along a line of longitude about 1km apart
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat+0.009, cell1.lon)
+	cl.Set(2, "2", cell1.lat+0.018, cell1.lon)
+	cl.Set(3, "3", cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+
+	// the caps around the first and last items overlap around the middle one
+	found := cl.ItemsWithinDistanceOfAny(
+		[][2]float64{{cell1.lat, cell1.lon}, {cell1.lat + 0.018, cell1.lon}}, 1100, params)
+	keys := make([]interface{}, 0, len(found))
+	for _, item := range found {
+		keys = append(keys, item.Key)
+	}
+	assert.Equal(t, []interface{}{0, 2, 1}, keys)
+	assert.Zero(t, found[0].DistanceMeters)
+	assert.Zero(t, found[1].DistanceMeters)
+	assert.InDelta(t, 1000, found[2].DistanceMeters, 10)
+	assert.Equal(t, "1", found[2].Contents)
+
+	assert.Empty(t, cl.ItemsWithinDistanceOfAny(nil, 1000, params))
+}
+
+func TestCollection_WithinDistanceBanded(t *testing.T) {
+	cl, all := randomCollection(500)
+	params := SearchCoveringParameters{MinLevel: 0, MaxLevel: 30, LevelMod: 1, MaxCells: 8}
+	// randomCollection sorts the items by their distance from cell1
+	within := func(maxMeters float64) []LocatedItem {
+		expected := make([]LocatedItem, 0)
+		for _, item := range all {
+			if item.DistanceMeters <= maxMeters {
+				expected = append(expected, item)
+			}
+		}
+		return expected
+	}
+
+	tests := []struct {
+		name          string
+		maxMeters     float64
+		bandMeters    float64
+		expectedBands int
+	}{
+		{"bands partition the items", 20000, 5000, 4},
+		{"the last band ends at the maximum distance", 20000, 6000, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found := make([]LocatedItem, 0)
+			bands := 0
+			err := cl.WithinDistanceBanded(cell1.lat, cell1.lon, test.maxMeters, test.bandMeters,
+				func(band int, items []LocatedItem) bool {
+					assert.Equal(t, bands, band)
+					bands++
+					for _, item := range items {
+						assert.Greater(t, item.DistanceMeters, float64(band)*test.bandMeters)
+						assert.LessOrEqual(t, item.DistanceMeters, float64(band+1)*test.bandMeters)
+					}
+					found = append(found, items...)
+					return true
+				}, params)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedBands, bands)
+			assert.Equal(t, within(test.maxMeters), found)
+		})
+	}
+
+	// the search stops once fn returns false
+	bands := 0
+	require.NoError(t, cl.WithinDistanceBanded(cell1.lat, cell1.lon, 20000, 5000, func(int, []LocatedItem) bool {
+		bands++
+		return false
+	}, params))
+	assert.Equal(t, 1, bands)
+
+	// an unbounded search ends once its bands reach the far side of the sphere
+	found := make([]LocatedItem, 0)
+	bands = 0
+	require.NoError(t, cl.WithinDistanceBanded(cell1.lat, cell1.lon, math.Inf(1), 1000000,
+		func(_ int, items []LocatedItem) bool {
+			bands++
+			found = append(found, items...)
+			return true
+		}, params))
+	assert.Equal(t, int(math.Ceil(math.Pi*EarthRadiusMeters/1000000)), bands)
+	assert.Equal(t, all, found)
+
+	for _, bandMeters := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		err := cl.WithinDistanceBanded(cell1.lat, cell1.lon, 20000, bandMeters, func(int, []LocatedItem) bool {
+			t.Errorf("band width %v searched a band", bandMeters)
+			return true
+		}, params)
+		assert.ErrorIs(t, err, ErrInvalidDistance, "band width %v", bandMeters)
+	}
+	assert.ErrorIs(t, cl.WithinDistanceBanded(91, cell1.lon, 20000, 5000, func(int, []LocatedItem) bool {
+		return true
+	}, params), ErrInvalidCoordinate)
+}
+
+func TestCollection_Nearest(t *testing.T) {
+	_, _, _, ok := NewCollection().Nearest(cell1.lat, cell1.lon, nearestParams)
+	assert.False(t, ok)
+
+	cl, all := randomCollection(500)
+	key, contents, distance, ok := cl.Nearest(cell1.lat, cell1.lon, nearestParams)
+	require.True(t, ok)
+	assert.Equal(t, all[0].Key, key)
+	assert.Equal(t, all[0].Contents, contents)
+	assert.Equal(t, all[0].DistanceMeters, distance)
+
+	// the only item is found however far away it is
+	far := NewCollection()
+	far.Set("manhattan", "manhattan", cell2.lat, cell2.lon)
+	key, _, distance, ok = far.Nearest(cell1.lat, cell1.lon, nearestParams)
+	require.True(t, ok)
+	assert.Equal(t, "manhattan", key)
+	assert.Equal(t, EarthDistanceMeters(NewPointFromLatLng(cell1.lat, cell1.lon), NewPointFromLatLng(cell2.lat, cell2.lon)), distance)
+}
+
+func TestCollection_NearestMatching(t *testing.T) {
+	cl, items := randomCollection(500)
+	// the only item in Manhattan, far outside of the first caps searched
+	cl.Set("manhattan", "manhattan", cell2.lat, cell2.lon)
+	multipleOf := func(n int) func(interface{}) bool {
+		return func(contents interface{}) bool {
+			i, ok := contents.(int)
+			return ok && i%n == 0
+		}
+	}
+	nearestMultiple := func(n int) LocatedItem {
+		for _, item := range items {
+			if item.Key.(int)%n == 0 {
+				return item
+			}
+		}
+		return LocatedItem{}
+	}
+	tests := []struct {
+		name             string
+		match            func(interface{}) bool
+		expectedContents interface{}
+		expectedDistance float64
+		expectedOk       bool
+	}{
+		{"the nearest item matching everything is the nearest item", func(interface{}) bool { return true },
+			items[0].Contents, items[0].DistanceMeters, true},
+		{"items that do not match are skipped", multipleOf(7),
+			nearestMultiple(7).Contents, nearestMultiple(7).DistanceMeters, true},
+		{"the search expands until it finds a match", func(contents interface{}) bool { return contents == "manhattan" },
+			"manhattan", EarthDistanceMeters(NewPointFromLatLng(cell1.lat, cell1.lon), NewPointFromLatLng(cell2.lat, cell2.lon)),
+			true},
+		{"nothing matches", func(interface{}) bool { return false }, nil, 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contents, distance, ok := cl.NearestMatching(cell1.lat, cell1.lon, nearestParams, test.match)
+			assert.Equal(t, test.expectedOk, ok)
+			assert.Equal(t, test.expectedContents, contents)
+			assert.Equal(t, test.expectedDistance, distance)
+		})
+	}
+
+	_, _, ok := NewCollection().NearestMatching(cell1.lat, cell1.lon, nearestParams, func(interface{}) bool { return true })
+	assert.False(t, ok)
+}
+
+func TestCollection_KNearestExcluding(t *testing.T) {
+	cl, items := randomCollection(100)
+	// exclude the ten nearest items along with one that is not stored
+	exclude := map[interface{}]bool{"missing": true}
+	for _, item := range items[:10] {
+		exclude[item.Key] = true
+	}
+	tests := []struct {
+		name     string
+		k        int
+		expected []LocatedItem
+	}{
+		{name: "Excluded items are skipped for farther ones", k: 5, expected: items[10:15]},
+		{name: "Every included item is found when k is larger than the collection", k: 200, expected: items[10:]},
+		{name: "Nothing is returned for k of zero", k: 0, expected: []LocatedItem{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.KNearestExcluding(cell1.lat, cell1.lon, test.k, exclude))
+		})
+	}
+	assert.Equal(t, items[:3], cl.KNearestExcluding(cell1.lat, cell1.lon, 3, nil))
+}
+
+func TestCollection_KNearestNeighbors(t *testing.T) {
+	cl, items := randomCollection(100)
+	contents := func(items []LocatedItem) []interface{} {
+		found := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			found = append(found, item.Contents)
+		}
+		return found
+	}
+	tests := []struct {
+		name     string
+		k        int
+		params   SearchCoveringParameters
+		expected []interface{}
+	}{
+		{
+			name:     "The k nearest items are returned in order",
+			k:        10,
+			params:   SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8},
+			expected: contents(items[:10]),
+		}, {
+			name:     "Coarse coverings find the same items",
+			k:        10,
+			params:   SearchCoveringParameters{MaxLevel: 4, MinLevel: 0, LevelMod: 1, MaxCells: 1},
+			expected: contents(items[:10]),
+		}, {
+			name:     "Every item is returned when k is larger than the collection",
+			k:        1000,
+			params:   SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8},
+			expected: contents(items),
+		}, {
+			name:     "Nothing is returned for k of zero",
+			params:   SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8},
+			expected: []interface{}{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, cl.KNearestNeighbors(cell1.lat, cell1.lon, test.k, test.params))
+		})
+	}
+
+	// ties at the last place go to the lowest keys
+	tied := NewCollection()
+	for _, key := range []int{2, 0, 1} {
+		tied.Set(key, key, cell1.lat, cell1.lon)
+	}
+	assert.Equal(t, []interface{}{0, 1}, tied.KNearestNeighbors(cell1.lat, cell1.lon, 2, SearchCoveringParameters{
+		MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}))
+}
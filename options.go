@@ -0,0 +1,49 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"time"
+)
+
+// Option configures optional behavior of a Collection created by NewCollection
+type Option func(*Collection)
+
+// WithUpdateTimestamps records the time at which each item was last set so that stale items can be removed with
+// DeleteOlderThan.
+func WithUpdateTimestamps() Option {
+	return func(c *Collection) {
+		c.trackUpdates = true
+	}
+}
+
+// WithClock sets the function the collection uses to read the current time. It defaults to time.Now and is
+// mostly useful to control time in tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Collection) {
+		c.now = now
+	}
+}
+
+// WithContentsCopier stores a copy of the contents, made by calling copyContents, whenever an item is set and
+// returns another copy whenever contents are read from the collection. This gives value semantics to contents
+// that are pointers or otherwise mutable, so that changing them outside the collection does not change what is
+// stored. Since the copier is called for every item on every write and every read, it should be cheap. By
+// default contents are stored and returned as-is.
+func WithContentsCopier(copyContents func(interface{}) interface{}) Option {
+	return func(c *Collection) {
+		c.copyContents = copyContents
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testSpot struct {
+	name string
+}
+
+func TestWithContentsCopier(t *testing.T) {
+	copySpot := func(contents interface{}) interface{} {
+		spot := *contents.(*testSpot)
+		return &spot
+	}
+	tests := []struct {
+		name         string
+		opts         []Option
+		expectedName string
+	}{
+		{name: "Contents are copied with a copier", opts: []Option{WithContentsCopier(copySpot)}, expectedName: "original"},
+		{name: "Contents are shared without a copier", expectedName: "mutated"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := NewCollection(test.opts...)
+			spot := &testSpot{name: "original"}
+			cl.Set(0, spot, cell1.lat, cell1.lon)
+			spot.name = "mutated"
+
+			read := cl.ItemByKey(0).(*testSpot)
+			assert.Equal(t, test.expectedName, read.name)
+			// mutating what was read does not change what is stored either
+			read.name = "read"
+			results, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, SearchCoveringParameters{
+				MaxLevel: 5, MinLevel: 5, LevelMod: 1, MaxCells: 5})
+			require.Len(t, results, 1)
+			if test.opts != nil {
+				assert.Equal(t, "original", results[0].(*testSpot).name)
+			} else {
+				assert.Same(t, spot, results[0])
+			}
+		})
+	}
+}
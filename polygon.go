@@ -0,0 +1,165 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/s2"
+)
+
+// polygonParams are the covering parameters used to find the items within a polygon
+var polygonParams = SearchCoveringParameters{MinLevel: 0, MaxLevel: maxCellLevel, LevelMod: 1, MaxCells: 32}
+
+// loopFromRing builds a loop from a closed ring of lng/lat pairs, whose last vertex repeats its first, validating
+// it the same way for every polygon search. Rings may be given in either orientation; the loop always encloses
+// the smaller of the two areas the ring separates. An error wrapping ErrInvalidPolygon is returned if the ring is
+// not closed, has a vertex out of range, has fewer than three distinct vertices or its edges cross.
+func loopFromRing(ring [][2]float64) (*s2.Loop, error) {
+	if len(ring) < 2 || ring[0] != ring[len(ring)-1] {
+		return nil, fmt.Errorf("%w: ring is not closed", ErrInvalidPolygon)
+	}
+	ring = ring[:len(ring)-1]
+	points := make([]s2.Point, 0, len(ring))
+	distinct := make(map[[2]float64]bool, len(ring))
+	for i, vertex := range ring {
+		if err := validateSearch(vertex[1], vertex[0], 0); err != nil {
+			return nil, fmt.Errorf("%w: vertex %d: %w", ErrInvalidPolygon, i, err)
+		}
+		points = append(points, NewPointFromLatLng(vertex[1], vertex[0]))
+		distinct[vertex] = true
+	}
+	if len(distinct) < 3 {
+		return nil, fmt.Errorf("%w: ring has %d distinct vertices, at least 3 are required", ErrInvalidPolygon, len(distinct))
+	}
+	loop := s2.LoopFromPoints(points)
+	if err := loop.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolygon, err)
+	}
+	if i, j, ok := crossingEdges(points); ok {
+		return nil, fmt.Errorf("%w: edges %d and %d cross", ErrInvalidPolygon, i, j)
+	}
+	loop.Normalize()
+	return loop, nil
+}
+
+// crossingEdges finds two non-adjacent edges of a loop that cross, where edge i runs from vertex i to the next
+// vertex. s2 does not check loops for crossing edges yet, so every pair of edges is checked, which is fine for
+// the rings of a few hundred vertices that polygon searches take.
+func crossingEdges(points []s2.Point) (i, j int, ok bool) {
+	n := len(points)
+	for i = 0; i < n; i++ {
+		for j = i + 2; j < n; j++ {
+			if i == 0 && j == n-1 {
+				// the first and last edges share the first vertex
+				continue
+			}
+			if s2.CrossingSign(points[i], points[(i+1)%n], points[j], points[(j+1)%n]) == s2.Cross {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// ItemsInPolygon returns all contents stored in the collection within the polygon, along with the boundaries of
+// the cells covering it. The polygon is a closed ring of longitude and latitude pairs, the same form as the
+// vertices of a SearchCoveringResult, whose last vertex repeats its first, and may be given in either orientation.
+// Unlike ItemsWithinDistance, every candidate in the covering cells is tested against the polygon itself, so
+// items just outside its edges are never returned. An error wrapping ErrInvalidPolygon is returned along with no
+// items if the ring has a vertex that is not a pair or fails the checks of loopFromRing, and an error wrapping
+// ErrInvalidCoveringParams if the parameters fail SearchCoveringParameters.Validate.
+func (c Collection) ItemsInPolygon(
+	loop [][]float64, params SearchCoveringParameters,
+) ([]interface{}, SearchCoveringResult, error) {
+	if err := params.Validate(); err != nil {
+		return []interface{}{}, SearchCoveringResult{}, err
+	}
+	ring := make([][2]float64, 0, len(loop))
+	for i, vertex := range loop {
+		if len(vertex) != 2 {
+			return []interface{}{}, SearchCoveringResult{},
+				fmt.Errorf("%w: vertex %d has %d coordinates, 2 are required", ErrInvalidPolygon, i, len(vertex))
+		}
+		ring = append(ring, [2]float64{vertex[0], vertex[1]})
+	}
+	polygon, err := loopFromRing(ring)
+	if err != nil {
+		return []interface{}{}, SearchCoveringResult{}, err
+	}
+	cellUnion := params.covering(polygon)
+	cellBounds := coveringResult(cellUnion, params.MergeCovering)
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	found := make([]interface{}, 0)
+	c.eachInCovering(cellUnion, func(_ interface{}, item collectionContents) bool {
+		if polygon.ContainsPoint(item.point) {
+			found = append(found, c.copied(item.contents))
+		}
+		return true
+	})
+	return found, cellBounds, nil
+}
+
+// NearestInPolygonBatch finds, for each query point, the nearest item that lies within the polygon. Query points
+// are latitude and longitude pairs and need not be within the polygon themselves. The polygon is a closed ring of
+// longitude and latitude pairs, validated as in ItemsInPolygon, and may be given in either orientation. The items
+// within the polygon are found once for the whole batch under a single read lock. The result holds the nearest item
+// for each query in the same order as the queries, or nil for every query if the polygon contains no items. Ties
+// in distance go to the lowest key. An error wrapping ErrInvalidPolygon is returned along with no items if the
+// polygon is not a valid ring.
+func (c Collection) NearestInPolygonBatch(queries [][2]float64, polygon [][2]float64) ([]*LocatedItem, error) {
+	loop, err := loopFromRing(polygon)
+	if err != nil {
+		return []*LocatedItem{}, err
+	}
+	cellUnion := polygonParams.covering(loop)
+
+	type polygonItem struct {
+		key      interface{}
+		item     collectionContents
+		position s2.Point
+	}
+	candidates := make([]polygonItem, 0)
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	c.eachInCovering(cellUnion, func(key interface{}, item collectionContents) bool {
+		position := item.point
+		if loop.ContainsPoint(position) {
+			candidates = append(candidates, polygonItem{key: key, item: item, position: position})
+		}
+		return true
+	})
+
+	nearest := make([]*LocatedItem, len(queries))
+	for i, query := range queries {
+		point := NewPointFromLatLng(query[0], query[1])
+		best, bestDistance := -1, math.Inf(1)
+		for j, candidate := range candidates {
+			distance := c.distance(point, candidate.position)
+			if distance > bestDistance || (distance == bestDistance && compareKeys(candidate.key, candidates[best].key) >= 0) {
+				continue
+			}
+			best, bestDistance = j, distance
+		}
+		if best >= 0 {
+			found := c.locatedItem(candidates[best].key, candidates[best].item, bestDistance)
+			nearest[i] = &found
+		}
+	}
+	return nearest, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// squareRing returns a closed lng/lat ring around a square of 2*halfWidth degrees centered on lat, lon
+func squareRing(lat, lon, halfWidth float64) [][2]float64 {
+	return [][2]float64{
+		{lon - halfWidth, lat - halfWidth},
+		{lon + halfWidth, lat - halfWidth},
+		{lon + halfWidth, lat + halfWidth},
+		{lon - halfWidth, lat + halfWidth},
+		{lon - halfWidth, lat - halfWidth},
+	}
+}
+
+func TestLoopFromRing(t *testing.T) {
+	square := squareRing(cell1.lat, cell1.lon, 0.01)
+	reversed := [][2]float64{square[3], square[2], square[1], square[0], square[3]}
+	tests := []struct {
+		name        string
+		ring        [][2]float64
+		expectedErr bool
+	}{
+		{name: "Closed rings are valid", ring: square},
+		{name: "Open rings are invalid", ring: square[:4], expectedErr: true},
+		{name: "Clockwise rings are valid", ring: reversed},
+		{name: "Rings with fewer than three distinct vertices are invalid", ring: [][2]float64{square[0], square[1], square[0]}, expectedErr: true},
+		{name: "Self-intersecting rings are invalid", ring: [][2]float64{square[0], square[2], square[1], square[3], square[0]}, expectedErr: true},
+		{name: "Rings with vertices out of range are invalid", ring: [][2]float64{square[0], square[1], {0, 91}, square[0]}, expectedErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			loop, err := loopFromRing(test.ring)
+			if test.expectedErr {
+				assert.ErrorIs(t, err, ErrInvalidPolygon)
+				return
+			}
+			require.NoError(t, err)
+			// whatever the orientation, the loop holds the small square rather than the rest of the world
+			assert.True(t, loop.ContainsPoint(NewPointFromLatLng(cell1.lat, cell1.lon)))
+			assert.Less(t, loop.Area(), 1e-6)
+		})
+	}
+}
+
+func TestCollection_ItemsInPolygon(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "inside", cell1.lat, cell1.lon)
+	cl.Set(1, "just inside", cell1.lat, cell1.lon+0.0099)
+	// within the coarse covering cells, but just past the eastern edge of the polygon
+	cl.Set(2, "just outside", cell1.lat, cell1.lon+0.0101)
+	cl.Set(3, "far away", cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 8, LevelMod: 1, MaxCells: 4}
+	square := squareRing(cell1.lat, cell1.lon, 0.01)
+	ring := make([][]float64, 0, len(square))
+	for _, vertex := range square {
+		ring = append(ring, []float64{vertex[0], vertex[1]})
+	}
+
+	approximate, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	require.Contains(t, approximate, "just outside")
+	results, covering, err := cl.ItemsInPolygon(ring, params)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []interface{}{"inside", "just inside"}, results)
+	assert.NotEmpty(t, covering)
+
+	tests := []struct {
+		name string
+		ring [][]float64
+	}{
+		{"open rings are invalid", ring[:4]},
+		{"rings with fewer than three distinct vertices are invalid", [][]float64{ring[0], ring[1], ring[0]}},
+		{"self-intersecting rings are invalid", [][]float64{ring[0], ring[2], ring[1], ring[3], ring[0]}},
+		{"vertices must be pairs", [][]float64{ring[0], ring[1], {cell1.lon}, ring[3], ring[0]}},
+		{"empty rings are invalid", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, ringCovering, ringErr := cl.ItemsInPolygon(test.ring, params)
+			assert.ErrorIs(t, ringErr, ErrInvalidPolygon)
+			assert.Equal(t, []interface{}{}, found)
+			assert.Equal(t, SearchCoveringResult{}, ringCovering)
+		})
+	}
+
+	found, _, err := cl.ItemsInPolygon(ring, SearchCoveringParameters{MinLevel: 8, MaxLevel: 8, MaxCells: 4})
+	assert.ErrorIs(t, err, ErrInvalidCoveringParams)
+	assert.Equal(t, []interface{}{}, found)
+}
+
+func TestCollection_NearestInPolygonBatch(t *testing.T) {
+	cl := NewCollection()
+	// two items inside a square of about 2km around downtown Chicago and one just outside of it
+	cl.Set(0, "west", cell1.lat, cell1.lon-0.005)
+	cl.Set(1, "east", cell1.lat, cell1.lon+0.005)
+	cl.Set(2, "outside", cell1.lat, cell1.lon+0.02)
+	polygon := squareRing(cell1.lat, cell1.lon, 0.01)
+
+	found, err := cl.NearestInPolygonBatch([][2]float64{
+		{cell1.lat, cell1.lon - 0.004},
+		// closer to the item outside of the polygon than to either item inside of it
+		{cell1.lat, cell1.lon + 0.03},
+		{cell2.lat, cell2.lon},
+	}, polygon)
+	require.NoError(t, err)
+	require.Len(t, found, 3)
+	for i, expected := range []interface{}{0, 1, 1} {
+		require.NotNil(t, found[i])
+		assert.Equal(t, expected, found[i].Key)
+	}
+	assert.Equal(t, "west", found[0].Contents)
+	assert.InDelta(t, 83, found[0].DistanceMeters, 1)
+
+	found, err = cl.NearestInPolygonBatch([][2]float64{{cell2.lat, cell2.lon}}, squareRing(cell2.lat, cell2.lon, 0.01))
+	require.NoError(t, err)
+	assert.Equal(t, []*LocatedItem{nil}, found)
+
+	// polygons are validated as in ItemsInPolygon, so open rings are invalid too
+	for _, invalid := range [][][2]float64{polygon[:2], polygon[:4]} {
+		found, err = cl.NearestInPolygonBatch([][2]float64{{cell1.lat, cell1.lon}}, invalid)
+		assert.ErrorIs(t, err, ErrInvalidPolygon)
+		assert.Equal(t, []*LocatedItem{}, found)
+	}
+}
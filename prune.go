@@ -0,0 +1,134 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+// PruneStrategy controls when the cells that deletes leave without any items are removed from the index. Pruning
+// on every delete keeps memory use proportional to the items stored at the cost of extra map operations on each
+// delete, while pruning in batches or manually makes deletes cheaper but holds on to the empty cells until then.
+type PruneStrategy struct {
+	// batchSize is the number of deletes between prunes, or 0 to only prune on Compact
+	batchSize int
+}
+
+// PruneImmediately removes empty cells as part of the delete that empties them.
+func PruneImmediately() PruneStrategy {
+	return PruneStrategy{batchSize: 1}
+}
+
+// PruneInBatches removes the cells emptied by deletes once every deletes deletes, so the cost of pruning is spread
+// over many deletes. Up to deletes items' worth of empty cells may be held at any time. Values less than 1 are
+// treated as 1.
+func PruneInBatches(deletes int) PruneStrategy {
+	return PruneStrategy{batchSize: max(deletes, 1)}
+}
+
+// PruneManually never removes empty cells on delete, leaving it to the caller to call Compact. This makes deletes
+// as cheap as possible, but empty cells accumulate until Compact is called.
+func PruneManually() PruneStrategy {
+	return PruneStrategy{}
+}
+
+// pruneState tracks the cells emptied by deletes that have not been pruned yet
+type pruneState struct {
+	strategy PruneStrategy
+	// pending are the cells that were emptied since the last prune
+	pending []itemIndex
+	// deletes is the number of deletes since the last prune
+	deletes int
+}
+
+// WithPruneStrategy sets when the collection removes cells emptied by deletes from its index. By default empty
+// cells are removed by the delete that empties them, see PruneImmediately, so that the index does not grow without
+// bound under churn.
+func WithPruneStrategy(strategy PruneStrategy) Option {
+	return func(c *Collection) {
+		c.pruning.strategy = strategy
+	}
+}
+
+// Compact removes every empty cell from the index, regardless of the prune strategy.
+func (c Collection) Compact() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for level, cells := range c.cells {
+		for cellID, keys := range cells {
+			if len(keys) == 0 {
+				delete(cells, cellID)
+			}
+		}
+		if len(cells) == 0 {
+			delete(c.cells, level)
+		}
+	}
+	c.pruning.pending, c.pruning.deletes = nil, 0
+}
+
+// CompactLevels removes every empty cell from the index like Compact, then rebuilds the map of cells of each level
+// at its current size. Go maps never shrink, so after a large collection has had most of its items deleted, its
+// level maps keep the memory of every cell they once held until they are rebuilt. This takes time proportional to
+// the number of occupied cells across all levels and blocks reads and writes until it is done.
+func (c Collection) CompactLevels() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for level, cells := range c.cells {
+		rebuilt := make(cellItems, len(cells))
+		for cellID, keys := range cells {
+			if len(keys) > 0 {
+				rebuilt[cellID] = keys
+			}
+		}
+		if len(rebuilt) == 0 {
+			delete(c.cells, level)
+			continue
+		}
+		c.cells[level] = rebuilt
+	}
+	c.pruning.pending, c.pruning.deletes = nil, 0
+}
+
+// deleted records that a delete emptied the given cells and prunes them according to the prune strategy. The
+// caller must hold the write lock.
+func (c Collection) deleted(emptied []itemIndex) {
+	switch c.pruning.strategy.batchSize {
+	case 0:
+		return
+	case 1:
+		c.prune(emptied)
+		return
+	}
+	c.pruning.pending = append(c.pruning.pending, emptied...)
+	c.pruning.deletes++
+	if c.pruning.deletes >= c.pruning.strategy.batchSize {
+		c.prune(c.pruning.pending)
+		c.pruning.pending, c.pruning.deletes = nil, 0
+	}
+}
+
+// prune removes the given cells from the index if they are still empty, along with any level left without
+// cells. The caller must hold the write lock.
+func (c Collection) prune(cells []itemIndex) {
+	for _, index := range cells {
+		levelCells, ok := c.cells[index.cellLevel]
+		if !ok {
+			continue
+		}
+		if keys, found := levelCells[index.cellID]; found && len(keys) == 0 {
+			delete(levelCells, index.cellID)
+		}
+		if len(levelCells) == 0 {
+			delete(c.cells, index.cellLevel)
+		}
+	}
+}
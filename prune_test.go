@@ -0,0 +1,137 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// numCells counts the cells in the collection's index, including empty ones
+func numCells(c Collection) int {
+	n := 0
+	for _, cells := range c.cells {
+		n += len(cells)
+	}
+	return n
+}
+
+func TestWithPruneStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy PruneStrategy
+		// expectedCells is the number of cells left after each delete
+		expectedCells []int
+	}{
+		{
+			name:          "Immediate pruning removes cells on every delete",
+			strategy:      PruneImmediately(),
+			expectedCells: []int{62, 31, 0},
+		}, {
+			name:          "Batched pruning removes cells once the batch is full",
+			strategy:      PruneInBatches(2),
+			expectedCells: []int{93, 31, 31},
+		}, {
+			name:          "Manual pruning never removes cells",
+			strategy:      PruneManually(),
+			expectedCells: []int{93, 93, 93},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl := NewCollection(WithPruneStrategy(test.strategy))
+			// three items on different cube faces share no cells
+			cl.Set(0, 0, 0, 0)
+			cl.Set(1, 1, 0, 90)
+			cl.Set(2, 2, 90, 0)
+			assert.Equal(t, 93, numCells(cl))
+			for i, expected := range test.expectedCells {
+				cl.Delete(i)
+				assert.Equal(t, expected, numCells(cl), "after deleting %d", i)
+			}
+
+			cl.Compact()
+			assert.Zero(t, numCells(cl))
+			assert.Empty(t, cl.cells)
+		})
+	}
+}
+
+func TestCollection_Compact(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, 0, cell1.lat, cell1.lon)
+	cl.Set(1, 1, cell2.lat, cell2.lon)
+	cl.Delete(1)
+	cl.Compact()
+	assert.Equal(t, 31, numCells(cl))
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, SearchCoveringParameters{
+		MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8})
+	assert.Equal(t, []interface{}{0}, found)
+}
+
+func TestCollection_DeleteShrinksIndex(t *testing.T) {
+	cl := NewCollection()
+	random := rand.New(rand.NewSource(1))
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 1000; i++ {
+			cl.Set(i, i, random.Float64()*180-90, random.Float64()*360-180)
+		}
+		for i := 0; i < 1000; i++ {
+			cl.Delete(i)
+		}
+		assert.Zero(t, numCells(cl), "round %d", round)
+		assert.Empty(t, cl.cells, "round %d", round)
+		assert.Empty(t, cl.keys, "round %d", round)
+	}
+}
+
+func TestCollection_CompactLevels(t *testing.T) {
+	cl := NewCollection(WithPruneStrategy(PruneImmediately()))
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		cl.Set(i, i, random.Float64()*180-90, random.Float64()*360-180)
+	}
+	for i := 100; i < 10000; i++ {
+		cl.Delete(i)
+	}
+	cells := numCells(cl)
+	// the deepest level maps grew to hold 10000 cells each and must be rebuilt to give that memory back
+	before := make(map[int]uintptr, len(cl.cells))
+	for level, levelCells := range cl.cells {
+		before[level] = reflect.ValueOf(levelCells).Pointer()
+	}
+
+	cl.CompactLevels()
+	assert.Equal(t, cells, numCells(cl))
+	assert.Len(t, cl.cells, len(before))
+	for level, levelCells := range cl.cells {
+		assert.NotEqual(t, before[level], reflect.ValueOf(levelCells).Pointer(), "level %d is rebuilt", level)
+		for cellID, keys := range levelCells {
+			assert.NotEmpty(t, keys, "cell %v at level %d", cellID, level)
+		}
+	}
+	params := SearchCoveringParameters{MaxLevel: 30, MinLevel: 0, LevelMod: 1, MaxCells: 8}
+	for i := 0; i < 100; i++ {
+		found, _ := cl.ItemsWithinDistance(cl.items[i].latitude, cl.items[i].longitude, 1, params)
+		assert.Contains(t, found, i)
+	}
+
+	cl.Clear()
+	cl.CompactLevels()
+	assert.Empty(t, cl.cells)
+}
@@ -0,0 +1,48 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"sort"
+)
+
+// ItemsWithinDistanceSortedBy performs the same search as ItemsWithinDistanceOnly and returns the contents found
+// ordered by less, which reports whether contents a sort before contents b. Contents that less considers equal
+// are ordered by key.
+func (c Collection) ItemsWithinDistanceSortedBy(
+	latitude, longitude, distanceMeters float64, less func(a, b interface{}) bool, params SearchCoveringParameters,
+) []interface{} {
+	if validateSearch(latitude, longitude, distanceMeters) != nil {
+		return []interface{}{}
+	}
+	center := NewPointFromLatLng(latitude, longitude)
+	cellUnion := params.covering(params.searchCap(center, distanceMeters, c.radiusMeters))
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	keys := make([]interface{}, 0)
+	c.eachInCovering(cellUnion, func(key interface{}, _ collectionContents) bool {
+		keys = append(keys, key)
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool { return compareKeys(keys[i], keys[j]) < 0 })
+	sort.SliceStable(keys, func(i, j int) bool { return less(c.items[keys[i]].contents, c.items[keys[j]].contents) })
+
+	foundItems := make([]interface{}, 0, len(keys))
+	for _, key := range keys {
+		foundItems = append(foundItems, c.copied(c.items[key].contents))
+	}
+	return foundItems
+}
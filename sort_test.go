@@ -0,0 +1,61 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_ItemsWithinDistanceSortedBy(t *testing.T) {
+	type spot struct {
+		name  string
+		price int
+	}
+	cl := NewCollection()
+	cl.Set(0, spot{name: "a", price: 20}, cell1.lat, cell1.lon)
+	cl.Set(1, spot{name: "b", price: 10}, cell1.lat, cell1.lon+0.001)
+	cl.Set(2, spot{name: "c", price: 20}, cell1.lat+0.001, cell1.lon)
+	cl.Set(3, spot{name: "d", price: 5}, cell1.lat, cell1.lon-0.001)
+	cl.Set(4, spot{name: "far", price: 1}, cell2.lat, cell2.lon)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+
+	tests := []struct {
+		name     string
+		less     func(a, b interface{}) bool
+		expected []string
+	}{
+		{
+			name:     "Contents are ordered by the comparator with ties ordered by key",
+			less:     func(a, b interface{}) bool { return a.(spot).price < b.(spot).price },
+			expected: []string{"d", "b", "a", "c"},
+		}, {
+			name:     "Reversed comparators keep ties ordered by key",
+			less:     func(a, b interface{}) bool { return a.(spot).price > b.(spot).price },
+			expected: []string{"a", "c", "b", "d"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found := cl.ItemsWithinDistanceSortedBy(cell1.lat, cell1.lon, 1000, test.less, params)
+			names := make([]string, 0, len(found))
+			for _, contents := range found {
+				names = append(names, contents.(spot).name)
+			}
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
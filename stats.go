@@ -0,0 +1,98 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// SearchStats describes the work done by a single search
+type SearchStats struct {
+	// Elapsed is the time taken by the search, including computing the covering
+	Elapsed time.Duration
+	// CellsCovered is the number of cells in the covering of the search area
+	CellsCovered int
+	// CellsNonEmpty is the number of covering cells that contain at least one item
+	CellsNonEmpty int
+	// CandidatesExamined is the number of items found in the covering cells
+	CandidatesExamined int
+	// ResultsReturned is the number of items returned by the search
+	ResultsReturned int
+	// Truncated reports whether the search found more items than its limit and left the rest out
+	Truncated bool
+	// CoverageRatio is the area of the covering cells divided by the area of the cap of distanceMeters around the
+	// center of the search. Both the coverer's covering and the cells bounding the cap that the search falls back
+	// to when the coverer finds none contain the cap, so the ratio is at least 1, and a high ratio means the
+	// covering reaches well past the search area and returns items the caller likely filters out, which a finer
+	// MaxLevel or more MaxCells would avoid. Searches of a zero distance have a ratio of +Inf.
+	CoverageRatio float64
+}
+
+// ItemsWithinDistanceStats performs the same search as ItemsWithinDistance but reports the work done by the
+// search instead of the covering. The counts are taken from the covering the search actually used, so they reflect
+// UseFastCovering, AutoMaxCells and the radius margins. Callers that do not need the statistics should use
+// ItemsWithinDistance. Invalid coordinates or distances return no items and empty statistics.
+func (c Collection) ItemsWithinDistanceStats(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) ([]interface{}, SearchStats) {
+	return c.ItemsWithinDistanceStatsLimited(latitude, longitude, distanceMeters, 0, params)
+}
+
+// ItemsWithinDistanceStatsLimited performs the same search as ItemsWithinDistanceStats but returns at most limit
+// items, setting Truncated in the statistics when it leaves items out. The nearest items are kept when
+// SortByDistance is set, and arbitrary ones otherwise. A limit that is not positive returns every item.
+func (c Collection) ItemsWithinDistanceStatsLimited(
+	latitude, longitude, distanceMeters float64, limit int, params SearchCoveringParameters,
+) ([]interface{}, SearchStats) {
+	if validateSearch(latitude, longitude, distanceMeters) != nil {
+		return []interface{}{}, SearchStats{}
+	}
+	start := time.Now()
+	center := NewPointFromLatLng(latitude, longitude)
+	cellUnion := params.covering(params.searchCap(center, distanceMeters, c.radiusMeters))
+	stats := SearchStats{
+		CellsCovered:  len(cellUnion),
+		CoverageRatio: coverageRatio(cellUnion, capFromCenterMeters(center, distanceMeters, c.radiusMeters)),
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, cell := range c.indexedCovering(cellUnion) {
+		if candidates := len(c.cells[cell.Level()][cell]); candidates > 0 {
+			stats.CellsNonEmpty++
+			stats.CandidatesExamined += candidates
+		}
+	}
+	foundItems := c.itemsNear(center, cellUnion, params)
+	if limit > 0 && len(foundItems) > limit {
+		foundItems = foundItems[:limit]
+		stats.Truncated = true
+	}
+	stats.ResultsReturned = len(foundItems)
+	stats.Elapsed = time.Since(start)
+	return foundItems, stats
+}
+
+// coverageRatio returns the area of the cells of cellUnion divided by the area of searchCap
+func coverageRatio(cellUnion s2.CellUnion, searchCap s2.Cap) float64 {
+	capArea := searchCap.Area()
+	if capArea == 0 {
+		return math.Inf(1)
+	}
+	return cellUnion.ExactArea() / capArea
+}
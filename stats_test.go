@@ -0,0 +1,142 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollection_ItemsWithinDistanceStats(t *testing.T) {
+	cl := NewCollection()
+	// two items share a level 10 cell in Chicago, a third is in a neighboring level 10 cell and a fourth is in
+	// Manhattan, well outside the search
+	chicago := cell1.cellID.Parent(10)
+	neighbor := chicago.EdgeNeighbors()[0]
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", chicago.LatLng().Lat.Degrees(), chicago.LatLng().Lng.Degrees())
+	cl.Set(2, "2", neighbor.LatLng().Lat.Degrees(), neighbor.LatLng().Lng.Degrees())
+	cl.Set(3, "3", cell2.lat, cell2.lon)
+
+	params := SearchCoveringParameters{MinLevel: 10, MaxLevel: 10, LevelMod: 1, MaxCells: 20}
+	searchCap := params.searchCap(NewPointFromLatLng(cell1.lat, cell1.lon), 30000, cl.radiusMeters)
+	cellUnion := params.covering(searchCap)
+	assert.Contains(t, cellUnion, chicago)
+	assert.Contains(t, cellUnion, neighbor)
+
+	results, stats := cl.ItemsWithinDistanceStats(cell1.lat, cell1.lon, 30000, params)
+	assert.ElementsMatch(t, []interface{}{"0", "1", "2"}, results)
+	assert.Equal(t, len(cellUnion), stats.CellsCovered)
+	assert.Equal(t, 2, stats.CellsNonEmpty)
+	assert.Equal(t, 3, stats.CandidatesExamined)
+	assert.Equal(t, 3, stats.ResultsReturned)
+	assert.False(t, stats.Truncated)
+	assert.Positive(t, stats.Elapsed)
+}
+
+func TestCollection_ItemsWithinDistanceStatsLimited(t *testing.T) {
+	cl, _ := randomCollection(100)
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8, SortByDistance: true}
+	all, unlimited := cl.ItemsWithinDistanceStats(cell1.lat, cell1.lon, 30000, params)
+	require.Greater(t, unlimited.ResultsReturned, 3)
+	tests := []struct {
+		name              string
+		limit             int
+		expectedReturned  int
+		expectedTruncated bool
+	}{
+		{"limits below the number of results truncate them", 3, 3, true},
+		{"limits at the number of results do not truncate them", unlimited.ResultsReturned,
+			unlimited.ResultsReturned, false},
+		{"non-positive limits return every result", 0, unlimited.ResultsReturned, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, stats := cl.ItemsWithinDistanceStatsLimited(cell1.lat, cell1.lon, 30000, test.limit, params)
+			assert.Len(t, results, test.expectedReturned)
+			assert.Equal(t, test.expectedReturned, stats.ResultsReturned)
+			assert.Equal(t, test.expectedTruncated, stats.Truncated)
+			assert.Equal(t, unlimited.CandidatesExamined, stats.CandidatesExamined)
+			// the nearest items are kept
+			assert.Equal(t, all[:test.expectedReturned], results)
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistanceStats_covering(t *testing.T) {
+	cl := NewCollection()
+	cl.Set(0, "0", cell1.lat, cell1.lon)
+	cl.Set(1, "1", cell1.lat+0.01, cell1.lon)
+	cl.Set(2, "2", cell2.lat, cell2.lon)
+	tests := []struct {
+		name   string
+		params SearchCoveringParameters
+	}{
+		{"covering", SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}},
+		{"fast covering", SearchCoveringParameters{
+			MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8, UseFastCovering: true}},
+		{"automatic max cells", SearchCoveringParameters{
+			MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8, AutoMaxCells: true}},
+		{"sorted by distance", SearchCoveringParameters{
+			MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8, SortByDistance: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			results, covering := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 5000, test.params)
+			statsResults, stats := cl.ItemsWithinDistanceStats(cell1.lat, cell1.lon, 5000, test.params)
+			assert.ElementsMatch(t, results, statsResults)
+			if test.params.SortByDistance {
+				assert.Equal(t, []interface{}{"0", "1"}, statsResults)
+			}
+			assert.Equal(t, len(covering), stats.CellsCovered)
+			assert.Equal(t, len(results), stats.ResultsReturned)
+		})
+	}
+}
+
+func TestCollection_ItemsWithinDistanceStats_coverageRatio(t *testing.T) {
+	cl := NewCollection()
+	tests := []struct {
+		name               string
+		distanceMeters     float64
+		params             SearchCoveringParameters
+		minRatio, maxRatio float64
+	}{
+		{"fine coverings are close to the cap", 1000,
+			SearchCoveringParameters{MinLevel: 8, MaxLevel: 18, LevelMod: 1, MaxCells: 100}, 1, 1.2},
+		{"coarse coverings overshoot the cap", 1000,
+			SearchCoveringParameters{MinLevel: 8, MaxLevel: 10, LevelMod: 1, MaxCells: 8}, 10, math.Inf(1)},
+		{"single cell coverings", 0,
+			SearchCoveringParameters{MinLevel: 30, MaxLevel: 30, LevelMod: 1, MaxCells: 8}, math.Inf(1), math.Inf(1)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, stats := cl.ItemsWithinDistanceStats(cell1.lat, cell1.lon, test.distanceMeters, test.params)
+			assert.GreaterOrEqual(t, stats.CoverageRatio, test.minRatio)
+			assert.LessOrEqual(t, stats.CoverageRatio, test.maxRatio)
+		})
+	}
+
+	// the cells a search falls back to when the coverer finds none contain the cap too
+	params := SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+	for _, distanceMeters := range []float64{1, 1000, 100000} {
+		searchCap := capFromCenterMeters(NewPointFromLatLng(cell1.lat, cell1.lon), distanceMeters, cl.radiusMeters)
+		ratio := coverageRatio(params.covering(uncoverableRegion{searchCap}), searchCap)
+		assert.GreaterOrEqual(t, ratio, 1.0, "distance %v", distanceMeters)
+	}
+}
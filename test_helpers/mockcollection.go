@@ -19,17 +19,29 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-// MockCollection provides a mock LocationCollection for use in tests
+// MockCollection provides a mock LocationCollection, Reader and Writer for use in tests
 type MockCollection struct {
 	mock.Mock
 }
 
+var (
+	_ geocollection.Reader             = (*MockCollection)(nil)
+	_ geocollection.Writer             = (*MockCollection)(nil)
+	_ geocollection.LocationCollection = (*MockCollection)(nil)
+)
+
 // ItemsWithinDistance is a mocked version of ItemsWithinDistance
 func (m *MockCollection) ItemsWithinDistance(latitude, longitude, distanceMeters float64, params geocollection.SearchCoveringParameters) ([]interface{}, geocollection.SearchCoveringResult) {
 	args := m.Called(latitude, longitude, distanceMeters, params)
 	return args.Get(0).([]interface{}), args.Get(1).(geocollection.SearchCoveringResult)
 }
 
+// ItemsWithinDistanceOnly is a mocked version of ItemsWithinDistanceOnly
+func (m *MockCollection) ItemsWithinDistanceOnly(latitude, longitude, distanceMeters float64, params geocollection.SearchCoveringParameters) []interface{} {
+	args := m.Called(latitude, longitude, distanceMeters, params)
+	return args.Get(0).([]interface{})
+}
+
 // Set is a mocked version of Set
 func (m *MockCollection) Set(key, contents interface{}, latitude, longitude float64) {
 	m.Called(key, contents, latitude, longitude)
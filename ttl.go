@@ -0,0 +1,115 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"time"
+)
+
+// SetWithTTL adds or updates an item like Set, except that the item expires once ttl has passed, as measured by
+// the collection's clock. Expired items are left out of searches, lookups and iteration straight away, but stay
+// in memory until PurgeExpired removes them, so counts taken from the index, such as DensestCell and KNearestCells,
+// include them until then. Setting the item again, with Set or SetWithTTL, replaces its expiry,
+// and a ttl of zero or less stores an item that never expires, as Set does. Expiry times are kept by GobEncode
+// and the WAL, but not by WriteNDJSON, which skips expired items and writes the rest as never expiring.
+func (c Collection) SetWithTTL(key, contents interface{}, latitude, longitude float64, ttl time.Duration) {
+	newContents := c.newContents(contents, latitude, longitude)
+	if ttl > 0 {
+		newContents.expiresAt = c.now().Add(ttl)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.store(key, newContents)
+}
+
+// Touch resets the expiry of the item stored by key to ttl from now, as SetWithTTL would, along with its update
+// time when WithUpdateTimestamps is set, without changing its contents or location or reindexing it. This is the
+// heartbeat of items that are kept alive while they are tracked. A ttl of zero or less makes the item never
+// expire. Touch returns false and changes nothing if the key is not stored or its item has already expired.
+func (c Collection) Touch(key interface{}, ttl time.Duration) bool {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.now().Add(ttl)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	item, ok := c.liveItem(key)
+	if !ok {
+		return false
+	}
+	item.expiresAt = expiresAt
+	item.updatedAt = c.updateTime()
+	c.store(key, item)
+	return true
+}
+
+// WithOnExpire calls fn with the key and contents of every expired item that PurgeExpired removes, once per item,
+// so that callers can react to items expiring, such as by releasing resources held by their contents. It is not
+// called for items that are deleted or replaced explicitly, even if they have expired. fn is called after the
+// write lock is released, so it may use the collection.
+func WithOnExpire(fn func(key, contents interface{})) Option {
+	return func(c *Collection) {
+		c.onExpire = fn
+	}
+}
+
+// PurgeExpired removes every expired item from the collection and returns the number of items removed. Collections
+// created with WithOnExpire call its callback for each of them once the items are removed.
+func (c Collection) PurgeExpired() int {
+	c.mutex.Lock()
+	now := c.now()
+	expired := make([]KeyedItem, 0)
+	for key, item := range c.items {
+		if item.expired(now) {
+			c.delete(key)
+			expired = append(expired, KeyedItem{Key: key, Contents: item.contents})
+		}
+	}
+	c.mutex.Unlock()
+	if c.onExpire != nil {
+		for _, item := range expired {
+			c.onExpire(item.Key, item.Contents)
+		}
+	}
+	return len(expired)
+}
+
+// expired reports whether the item has an expiry time that is not after now
+func (item collectionContents) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && !now.Before(item.expiresAt)
+}
+
+// liveItem returns the item stored for key, or false if the key is not stored or the item has expired. The caller
+// must hold the read lock.
+func (c Collection) liveItem(key interface{}) (collectionContents, bool) {
+	item, ok := c.items[key]
+	if !ok || item.expired(c.now()) {
+		return collectionContents{}, false
+	}
+	return item, true
+}
+
+// liveKeys returns the keys of every item that has not expired, in no particular order. The caller must hold the
+// read lock.
+func (c Collection) liveKeys() []interface{} {
+	now := c.now()
+	keys := make([]interface{}, 0, len(c.items))
+	for key, item := range c.items {
+		if !item.expired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,265 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollection_SetWithTTL(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	cl := NewCollection(WithClock(clock))
+	cl.SetWithTTL(0, "expiring", cell1.lat, cell1.lon, time.Hour)
+	cl.SetWithTTL(1, "permanent", cell1.lat, cell1.lon, 0)
+	cl.Set(2, "set", cell1.lat, cell1.lon)
+
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.ElementsMatch(t, []interface{}{"expiring", "permanent", "set"}, found)
+	assert.Equal(t, "expiring", cl.ItemByKey(0))
+
+	now = now.Add(time.Hour)
+	tests := []struct {
+		name   string
+		lookup func() interface{}
+	}{
+		{"ItemsWithinDistance", func() interface{} {
+			found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+			return len(found)
+		}},
+		{"ItemsWithinDistanceWithMeta", func() interface{} {
+			found, _ := cl.ItemsWithinDistanceWithMeta(cell1.lat, cell1.lon, 1000, params)
+			return len(found)
+		}},
+		{"KNearestNeighbors", func() interface{} {
+			return len(cl.KNearestNeighbors(cell1.lat, cell1.lon, 3, params))
+		}},
+		{"ItemsAtPoint", func() interface{} { return len(cl.ItemsAtPoint(cell1.lat, cell1.lon)) }},
+		{"ItemsAtLocation", func() interface{} { return len(cl.ItemsAtLocation(cell1.lat, cell1.lon)) }},
+		{"GetItems", func() interface{} { return len(cl.GetItems(10, 0)) }},
+		{"Keys", func() interface{} { return len(cl.Keys()) }},
+		{"FrozenSnapshot", func() interface{} { return cl.FrozenSnapshot().Len() }},
+		{"ForEach", func() interface{} {
+			count := 0
+			cl.ForEach(func(_, _ interface{}, _, _ float64) bool {
+				count++
+				return true
+			})
+			return count
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name+" skips expired items", func(t *testing.T) {
+			assert.Equal(t, 2, test.lookup())
+		})
+	}
+	assert.Nil(t, cl.ItemByKey(0))
+	assert.False(t, cl.Has(0))
+	_, _, ok := cl.GetLocation(0)
+	assert.False(t, ok)
+	assert.False(t, cl.UpdateLocation(0, cell2.lat, cell2.lon))
+	assert.Equal(t, 2, cl.Count())
+	assert.Len(t, cl.GetItems(10, 0), cl.Count())
+
+	// setting an expired key again stores it afresh
+	actual, loaded := cl.GetOrSet(0, "again", cell1.lat, cell1.lon)
+	assert.Equal(t, "again", actual)
+	assert.False(t, loaded)
+	assert.Equal(t, "again", cl.ItemByKey(0))
+}
+
+func TestCollection_PurgeExpired(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cl := NewCollection(WithClock(func() time.Time { return now }))
+	cl.SetWithTTL(0, "0", cell1.lat, cell1.lon, time.Minute)
+	cl.SetWithTTL(1, "1", cell2.lat, cell2.lon, time.Hour)
+	cl.Set(2, "2", cell2.lat, cell2.lon)
+	// refreshing an item without a TTL removes its expiry
+	cl.SetWithTTL(3, "3", cell2.lat, cell2.lon, time.Minute)
+	cl.Set(3, "3", cell2.lat, cell2.lon)
+
+	assert.Zero(t, cl.PurgeExpired())
+	now = now.Add(time.Minute)
+	assert.Equal(t, 1, cl.PurgeExpired())
+	assert.Equal(t, 3, cl.Count())
+	assert.Empty(t, cl.keys[0])
+	now = now.Add(time.Hour)
+	assert.Equal(t, 1, cl.PurgeExpired())
+	assert.ElementsMatch(t, []interface{}{2, 3}, cl.Keys())
+	assert.Equal(t, 2, cl.Count())
+}
+
+func TestCollection_SetWithTTL_cache(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cl := NewCollection(WithResultCacheTTL(time.Hour), WithClock(func() time.Time { return now }))
+	cl.SetWithTTL(0, "0", cell1.lat, cell1.lon, time.Minute)
+	params := SearchCoveringParameters{MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8}
+	found, _ := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Equal(t, []interface{}{"0"}, found)
+	// the cached results expire along with the item
+	now = now.Add(time.Minute)
+	found, _ = cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, params)
+	assert.Empty(t, found)
+}
+
+func TestCollection_SetWithTTL_gob(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	cl := NewCollection(WithClock(clock))
+	cl.SetWithTTL("a", "a", cell1.lat, cell1.lon, time.Minute)
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(cl))
+
+	decoded := NewCollection(WithClock(clock))
+	require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+	assert.Equal(t, cl.items, decoded.items)
+	now = now.Add(time.Minute)
+	assert.False(t, decoded.Has("a"))
+	assert.Zero(t, decoded.Count())
+}
+
+func TestCollection_SetWithTTL_wal(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	var wal bytes.Buffer
+	cl := NewCollection(WithClock(clock), WithWAL(&wal))
+	cl.Set("a", "old", cell1.lat, cell1.lon)
+	cl.SetWithTTL("a", "new", cell1.lat, cell1.lon, time.Minute)
+	cl.SetWithTTL("b", "b", cell1.lat, cell1.lon, time.Hour)
+	cl.Set("c", "c", cell1.lat, cell1.lon)
+
+	// replay after the first item has expired
+	now = now.Add(2 * time.Minute)
+	var rewal bytes.Buffer
+	replayed := NewCollection(WithClock(clock), WithWAL(&rewal))
+	require.NoError(t, ReplayWAL(&wal, &replayed))
+	assert.NotContains(t, replayed.items, "a")
+	// each replayed set is logged once by a collection with a WAL of its own, along with its expiry
+	decoder := json.NewDecoder(&rewal)
+	ops := make([]string, 0)
+	for decoder.More() {
+		var record walRecord
+		require.NoError(t, decoder.Decode(&record))
+		ops = append(ops, record.Op+" "+record.Key.(string))
+		if record.Key == "b" {
+			require.NotNil(t, record.ExpiresAt)
+			assert.True(t, now.Add(time.Hour-2*time.Minute).Equal(*record.ExpiresAt))
+		}
+	}
+	assert.Equal(t, []string{"set a", "delete a", "set b", "set c"}, ops)
+	assert.Equal(t, "b", replayed.ItemByKey("b"))
+	assert.Equal(t, "c", replayed.ItemByKey("c"))
+
+	// the item that has not expired yet keeps its original expiry
+	now = now.Add(time.Hour - 2*time.Minute)
+	assert.False(t, replayed.Has("b"))
+	assert.True(t, replayed.Has("c"))
+}
+
+func TestCollection_Remove_expired(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cl := NewCollection(WithClock(func() time.Time { return now }))
+	cl.SetWithTTL(0, "0", cell1.lat, cell1.lon, time.Minute)
+	cl.SetWithTTL(1, "1", cell1.lat, cell1.lon, time.Minute)
+	require.NoError(t, cl.Remove(0))
+
+	now = now.Add(time.Minute)
+	assert.ErrorIs(t, cl.Remove(1), ErrKeyNotFound)
+	assert.Empty(t, cl.items)
+	assert.Empty(t, cl.keys)
+}
+
+func TestTypedCollection_ItemByKey_expired(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cl := NewTypedCollection[int, string](WithClock(func() time.Time { return now }))
+	cl.Untyped().SetWithTTL(0, "0", cell1.lat, cell1.lon, time.Minute)
+	found, ok := cl.ItemByKey(0)
+	assert.True(t, ok)
+	assert.Equal(t, "0", found)
+
+	now = now.Add(time.Minute)
+	found, ok = cl.ItemByKey(0)
+	assert.False(t, ok)
+	assert.Empty(t, found)
+}
+
+func TestCollection_Touch(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cl := NewCollection(WithClock(func() time.Time { return now }), WithUpdateTimestamps())
+	cl.SetWithTTL(0, "0", cell1.lat, cell1.lon, time.Minute)
+	cl.Set(1, "1", cell1.lat, cell1.lon)
+	keys := maps.Clone(cl.keys)
+	assert.False(t, cl.Touch("missing", time.Minute))
+
+	// the touched item survives past its original expiry without being reindexed
+	now = now.Add(50 * time.Second)
+	touchedAt := now
+	assert.True(t, cl.Touch(0, time.Minute))
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, "0", cl.ItemByKey(0))
+	assert.Equal(t, keys, cl.keys)
+	// and its update time is refreshed, unlike that of the untouched item
+	assert.Equal(t, 1, cl.DeleteOlderThan(touchedAt))
+	assert.True(t, cl.Has(0))
+	assert.False(t, cl.Has(1))
+
+	now = now.Add(30 * time.Second)
+	assert.False(t, cl.Has(0))
+	assert.False(t, cl.Touch(0, time.Minute))
+
+	// touching with no TTL makes an item permanent
+	cl.SetWithTTL(1, "1", cell1.lat, cell1.lon, time.Minute)
+	assert.True(t, cl.Touch(1, 0))
+	now = now.Add(time.Hour)
+	assert.True(t, cl.Has(1))
+}
+
+func TestWithOnExpire(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	expired := make(map[interface{}]interface{})
+	var cl Collection
+	cl = NewCollection(WithClock(func() time.Time { return now }), WithOnExpire(func(key, contents interface{}) {
+		// the lock is released before the callback runs
+		assert.False(t, cl.Has(key))
+		expired[key] = contents
+	}))
+	cl.SetWithTTL(0, "0", cell1.lat, cell1.lon, time.Minute)
+	cl.SetWithTTL(1, "1", cell1.lat, cell1.lon, time.Minute)
+	cl.SetWithTTL(2, "2", cell1.lat, cell1.lon, time.Hour)
+	cl.Set(3, "3", cell1.lat, cell1.lon)
+	assert.Zero(t, cl.PurgeExpired())
+	assert.Empty(t, expired)
+
+	now = now.Add(time.Minute)
+	// explicitly deleting an expired item does not call the callback
+	cl.Delete(1)
+	assert.Equal(t, 1, cl.PurgeExpired())
+	assert.Equal(t, map[interface{}]interface{}{0: "0"}, expired)
+
+	// each item is only reported once
+	now = now.Add(time.Hour)
+	assert.Equal(t, 1, cl.PurgeExpired())
+	assert.Zero(t, cl.PurgeExpired())
+	assert.Equal(t, map[interface{}]interface{}{0: "0", 2: "2"}, expired)
+}
@@ -0,0 +1,78 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+// TypedCollection is a Collection with keys of type K and contents of type V, so that callers do not need type
+// assertions and mismatched types are caught at compile time. Rather than being generic throughout, it wraps a
+// Collection, whose index stores K and V as interface values, so keys and contents are boxed as they are in an
+// untyped collection. The Collection remains available through Untyped for the operations that TypedCollection
+// does not wrap.
+type TypedCollection[K comparable, V any] struct {
+	collection Collection
+}
+
+// NewTypedCollection creates a new typed collection configured with the given options
+func NewTypedCollection[K comparable, V any](opts ...Option) TypedCollection[K, V] {
+	return TypedCollection[K, V]{collection: NewCollection(opts...)}
+}
+
+// Untyped returns the Collection that holds the items of the typed collection. Items should only be set through
+// the typed collection: contents that are not of type V read back through it as the zero value of V, with
+// ItemByKey still reporting the key as stored, and keys that are not of type K can't be read through it at all.
+func (t TypedCollection[K, V]) Untyped() Collection {
+	return t.collection
+}
+
+// Set adds or updates an item, see Collection.Set
+func (t TypedCollection[K, V]) Set(key K, contents V, latitude, longitude float64) {
+	t.collection.Set(key, contents, latitude, longitude)
+}
+
+// Delete removes an item by its key, see Collection.Delete
+func (t TypedCollection[K, V]) Delete(key K) {
+	t.collection.Delete(key)
+}
+
+// ItemByKey returns the contents stored by key and whether the key is stored, leaving out expired items
+func (t TypedCollection[K, V]) ItemByKey(key K) (V, bool) {
+	t.collection.mutex.RLock()
+	defer t.collection.mutex.RUnlock()
+	item, ok := t.collection.liveItem(key)
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return typedContents[V](t.collection.copied(item.contents)), true
+}
+
+// ItemsWithinDistance returns the contents within distanceMeters of the given latitude and longitude, see
+// Collection.ItemsWithinDistance
+func (t TypedCollection[K, V]) ItemsWithinDistance(
+	latitude, longitude, distanceMeters float64, params SearchCoveringParameters,
+) ([]V, SearchCoveringResult) {
+	found, bounds := t.collection.ItemsWithinDistance(latitude, longitude, distanceMeters, params)
+	contents := make([]V, 0, len(found))
+	for _, item := range found {
+		contents = append(contents, typedContents[V](item))
+	}
+	return contents, bounds
+}
+
+// typedContents converts contents stored by a TypedCollection back to V. Nil contents, which are stored for the
+// zero value of interface types, convert to the zero value.
+func typedContents[V any](contents interface{}) V {
+	typed, _ := contents.(V)
+	return typed
+}
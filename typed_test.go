@@ -0,0 +1,62 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedCollection(t *testing.T) {
+	type spot struct {
+		name string
+	}
+	cl := NewTypedCollection[int, spot]()
+	cl.Set(0, spot{name: "chicago"}, cell1.lat, cell1.lon)
+	cl.Set(1, spot{name: "new york"}, cell2.lat, cell2.lon)
+
+	found, ok := cl.ItemByKey(0)
+	assert.True(t, ok)
+	assert.Equal(t, spot{name: "chicago"}, found)
+	_, ok = cl.ItemByKey(2)
+	assert.False(t, ok)
+
+	nearby, bounds := cl.ItemsWithinDistance(cell1.lat, cell1.lon, 1000, SearchCoveringParameters{
+		MaxLevel: 16, MinLevel: 8, LevelMod: 1, MaxCells: 8})
+	assert.Equal(t, []spot{{name: "chicago"}}, nearby)
+	assert.NotEmpty(t, bounds)
+
+	cl.Delete(0)
+	_, ok = cl.ItemByKey(0)
+	assert.False(t, ok)
+	assert.Equal(t, spot{name: "new york"}, cl.Untyped().ItemByKey(1))
+}
+
+func TestTypedCollection_interfaceContents(t *testing.T) {
+	cl := NewTypedCollection[string, error]()
+	cl.Set("a", nil, cell1.lat, cell1.lon)
+	found, ok := cl.ItemByKey("a")
+	assert.True(t, ok)
+	assert.Nil(t, found)
+}
+
+func TestTypedCollection_mismatchedContents(t *testing.T) {
+	cl := NewTypedCollection[int, string]()
+	cl.Untyped().Set(0, 0, cell1.lat, cell1.lon)
+	found, ok := cl.ItemByKey(0)
+	assert.True(t, ok)
+	assert.Equal(t, "", found)
+}
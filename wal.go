@@ -0,0 +1,167 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Operations recorded in the write-ahead log
+const (
+	walSet    = "set"
+	walDelete = "delete"
+	walClear  = "clear"
+)
+
+// walRecord is a single mutation in the write-ahead log
+type walRecord struct {
+	Op       string      `json:"op"`
+	Key      interface{} `json:"key,omitempty"`
+	Contents interface{} `json:"contents,omitempty"`
+	Heading  *float64    `json:"heading,omitempty"`
+	// Cell is the token of the cell of items that are not indexed from a leaf cell, i.e. set with SetToken
+	Cell      string  `json:"cell,omitempty"`
+	Latitude  float64 `json:"lat,omitempty"`
+	Longitude float64 `json:"lon,omitempty"`
+	// ExpiresAt is when items set with SetWithTTL expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// walState is the write-ahead log of a collection and the first error writing to it
+type walState struct {
+	encoder *json.Encoder
+	err     error
+}
+
+// WithWAL appends every change made to the collection to w as a write-ahead log, so that the collection can be
+// restored by loading its last snapshot and replaying the log with ReplayWAL. Each change is written as one line
+// of JSON after it is applied and while the write lock is still held, so the log is in the same order as the
+// changes. Moving an item is logged as deleting it and setting it again. The collection does not buffer, flush or
+// sync w; callers that need the log to survive a crash must fsync it themselves. Once a write to w fails, nothing
+// more is logged and the error is returned by WALError. Keys and contents must be serializable with
+// encoding/json.
+func WithWAL(w io.Writer) Option {
+	return func(c *Collection) {
+		c.wal = &walState{encoder: json.NewEncoder(w)}
+	}
+}
+
+// WALError returns the error that stopped the write-ahead log, if any
+func (c Collection) WALError() error {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.wal == nil {
+		return nil
+	}
+	return c.wal.err
+}
+
+// logSet records that item was stored for key. The caller must hold the write lock.
+func (c Collection) logSet(key interface{}, item collectionContents) {
+	if c.wal == nil {
+		return
+	}
+	record := walRecord{
+		Op:        walSet,
+		Key:       key,
+		Contents:  item.contents,
+		Latitude:  item.latitude,
+		Longitude: item.longitude,
+	}
+	if item.hasHeading {
+		record.Heading = &item.heading
+	}
+	if !item.cellID.IsLeaf() {
+		record.Cell = item.cellID.ToToken()
+	}
+	if !item.expiresAt.IsZero() {
+		record.ExpiresAt = &item.expiresAt
+	}
+	c.logRecord(record)
+}
+
+// logRecord writes a record to the write-ahead log unless it has already failed. The caller must hold the write
+// lock.
+func (c Collection) logRecord(record walRecord) {
+	if c.wal == nil || c.wal.err != nil {
+		return
+	}
+	if err := c.wal.encoder.Encode(record); err != nil {
+		c.wal.err = fmt.Errorf("failed to write %s of key %v to the WAL: %w", record.Op, record.Key, err)
+	}
+}
+
+// ReplayWAL applies the changes recorded in a write-ahead log written by a collection created with WithWAL to
+// into, in the order they were logged. It is meant to be applied to a snapshot taken before the first change in
+// the log. As with ReadNDJSON, keys and contents are decoded into the generic types of encoding/json. Items set
+// with a TTL expire at the time they were logged to expire, as measured by into's clock, and sets of items that
+// have already expired by then delete the key instead, since the item would only be hidden until purged.
+func ReplayWAL(r io.Reader, into *Collection) error {
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var record walRecord
+		if err := decoder.Decode(&record); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("failed to read WAL record %d: %w", line, err)
+		}
+		switch record.Op {
+		case walSet:
+			if record.ExpiresAt != nil && !into.now().Before(*record.ExpiresAt) {
+				into.Delete(record.Key)
+				continue
+			}
+			if err := into.replaySet(record); err != nil {
+				return fmt.Errorf("failed to replay WAL record %d: %w", line, err)
+			}
+		case walDelete:
+			into.Delete(record.Key)
+		case walClear:
+			into.Clear()
+		default:
+			return fmt.Errorf("failed to replay WAL record %d: unknown operation %q", line, record.Op)
+		}
+	}
+}
+
+// replaySet stores the item of a set record, with its cell or heading and its expiry, under a single write lock
+// and as a single store, so that the item is never stored without its expiry and is logged once
+func (c Collection) replaySet(record walRecord) error {
+	var item collectionContents
+	if record.Cell != "" {
+		cellID, err := c.tokenCell(record.Cell)
+		if err != nil {
+			return err
+		}
+		item = c.newCellContents(record.Contents, cellID)
+	} else {
+		item = c.newContents(record.Contents, record.Latitude, record.Longitude)
+		if record.Heading != nil {
+			item.heading, item.hasHeading = wrapDegrees(*record.Heading), true
+		}
+	}
+	if record.ExpiresAt != nil {
+		item.expiresAt = *record.ExpiresAt
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.store(record.Key, item)
+	return nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestReplayWAL(t *testing.T) {
+	var log bytes.Buffer
+	cl := NewCollection(WithWAL(&log))
+	cl.Set("a", "a", cell1.lat, cell1.lon)
+	cl.Set("b", "b", cell2.lat, cell2.lon)
+	cl.Set("c", "c", cell2.lat, cell2.lon)
+
+	var snapshot bytes.Buffer
+	require.NoError(t, cl.WriteNDJSON(&snapshot))
+	log.Reset()
+
+	cl.Set("a", "moved", cell2.lat, cell2.lon)
+	cl.Set("b", "updated", cell2.lat, cell2.lon)
+	cl.Delete("c")
+	cl.Delete("missing")
+	cl.SetWithHeading("d", "d", cell1.lat, cell1.lon, 90)
+	require.NoError(t, cl.SetToken("e", "e", cell1.cellID.Parent(12).ToToken()))
+	require.NoError(t, cl.WALError())
+
+	restored, err := ReadNDJSON(&snapshot)
+	require.NoError(t, err)
+	require.NoError(t, ReplayWAL(&log, &restored))
+	assert.Equal(t, cl.items, restored.items)
+	assert.Equal(t, cl.keys, restored.keys)
+
+	log.Reset()
+	cl.Clear()
+	require.NoError(t, ReplayWAL(&log, &restored))
+	assert.Zero(t, restored.Count())
+}
+
+func TestReplayWAL_errors(t *testing.T) {
+	cl := NewCollection()
+	assert.ErrorContains(t, ReplayWAL(strings.NewReader(`{"op": "set", "key": "a"}`+"\n{"), &cl), "record 2")
+	assert.ErrorContains(t, ReplayWAL(strings.NewReader(`{"op": "rename", "key": "a"}`), &cl), `unknown operation "rename"`)
+	assert.ErrorIs(t, ReplayWAL(strings.NewReader(`{"op": "set", "key": "a", "cell": "zz"}`), &cl), ErrInvalidCoordinate)
+}
+
+func TestWithWAL_writeError(t *testing.T) {
+	cl := NewCollection(WithWAL(failingWriter{}))
+	cl.Set("a", "a", cell1.lat, cell1.lon)
+	cl.Set("b", "b", cell1.lat, cell1.lon)
+	assert.ErrorContains(t, cl.WALError(), "set of key a")
+	// the change is applied even though it could not be logged
+	assert.Equal(t, 2, cl.Count())
+	assert.NoError(t, NewCollection().WALError())
+}
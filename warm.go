@@ -0,0 +1,43 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+// warmRadiusMeters is the radius of the searches run by Warm
+const warmRadiusMeters = 1000
+
+// warmParams are the covering parameters of the searches run by Warm
+var warmParams = SearchCoveringParameters{MinLevel: 8, MaxLevel: 16, LevelMod: 1, MaxCells: 8}
+
+// Warm runs up to sampleQueries searches centered on items in the collection so that the code paths and memory
+// used by searches are already exercised when the first real queries arrive. It is meant to be called once after
+// the collection is loaded by services that are sensitive to latency right after startup. Warming is best-effort
+// and optional: it does not change the contents of the collection or the results of later searches, and how much
+// it helps depends on the runtime.
+func (c Collection) Warm(sampleQueries int) {
+	c.mutex.RLock()
+	centers := make([][2]float64, 0, min(max(sampleQueries, 0), len(c.items)))
+	for _, item := range c.items {
+		if len(centers) >= sampleQueries {
+			break
+		}
+		centers = append(centers, [2]float64{item.latitude, item.longitude})
+	}
+	c.mutex.RUnlock()
+
+	for _, center := range centers {
+		c.ItemsWithinDistance(center[0], center[1], warmRadiusMeters, warmParams)
+		c.KNearestPage(center[0], center[1], 10, Cursor{})
+	}
+}
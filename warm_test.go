@@ -0,0 +1,42 @@
+// Copyright 2023 SpotHero
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package geocollection
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollection_Warm(t *testing.T) {
+	tests := []struct {
+		name          string
+		numItems      int
+		sampleQueries int
+	}{
+		{name: "Warms a populated collection", numItems: 100, sampleQueries: 10},
+		{name: "Asking for more queries than items is fine", numItems: 5, sampleQueries: 10},
+		{name: "Warms an empty collection", sampleQueries: 10},
+		{name: "Negative sample counts do nothing", numItems: 5, sampleQueries: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cl, _ := randomCollection(test.numItems)
+			before := cl.GetItems(test.numItems, 0)
+			assert.NotPanics(t, func() { cl.Warm(test.sampleQueries) })
+			assert.ElementsMatch(t, before, cl.GetItems(test.numItems, 0))
+		})
+	}
+}